@@ -0,0 +1,187 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package interstellar
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// AccountProperties represents the Database Account resource, which is the root of the resource model
+// See https://docs.microsoft.com/en-us/rest/api/cosmos-db/get-a-database-account for the latest documentation
+type AccountProperties struct {
+	// ID is the name of the database account.
+	ID string `json:"id"`
+	// ResourceID is a unique identifier of the account.
+	ResourceID string `json:"_rid,omitempty"`
+	// Self is the unique addressable URI for the resource.
+	Self string `json:"_self,omitempty"`
+	// Databases specifies the addressable path of the databases resource.
+	Databases string `json:"_dbs,omitempty"`
+	// WritableLocations lists the regions which accept writes for this account.
+	WritableLocations []AccountLocation `json:"writableLocations,omitempty"`
+	// ReadableLocations lists the regions which accept reads for this account.
+	ReadableLocations []AccountLocation `json:"readableLocations,omitempty"`
+	// EnableMultipleWriteLocations is true when the account is configured for multi-region writes.
+	EnableMultipleWriteLocations bool `json:"enableMultipleWriteLocations,omitempty"`
+	// UserConsistencyPolicy is the default consistency configured for the account.
+	UserConsistencyPolicy *AccountConsistencyPolicy `json:"userConsistencyPolicy,omitempty"`
+	// BackupPolicy is the backup configuration of the account, if reported.
+	BackupPolicy *AccountBackupPolicy `json:"backupPolicy,omitempty"`
+}
+
+// AccountLocation is a regional endpoint of the database account
+type AccountLocation struct {
+	// Name is the name of the region, such as "East US"
+	Name string `json:"name"`
+	// DatabaseAccountEndpoint is the endpoint of the account in this region
+	DatabaseAccountEndpoint string `json:"databaseAccountEndpoint"`
+}
+
+// AccountConsistencyPolicy is the default consistency policy of the account
+type AccountConsistencyPolicy struct {
+	// DefaultConsistencyLevel is the account's default consistency level, such as "Session" or "BoundedStaleness"
+	DefaultConsistencyLevel string `json:"defaultConsistencyLevel"`
+	// MaxStalenessPrefix is the maximum number of versions reads may lag behind writes with BoundedStaleness consistency
+	MaxStalenessPrefix int64 `json:"maxStalenessPrefix,omitempty"`
+	// MaxStalenessIntervalInSeconds is the maximum time reads may lag behind writes with BoundedStaleness consistency
+	MaxStalenessIntervalInSeconds int64 `json:"maxIntervalInSeconds,omitempty"`
+}
+
+// BackupPolicyType is the type of backup configured on an account
+type BackupPolicyType string
+
+const (
+	// BackupPolicyPeriodic takes full backups of the account at a fixed interval
+	BackupPolicyPeriodic = BackupPolicyType("Periodic")
+	// BackupPolicyContinuous allows point-in-time restore of the account
+	BackupPolicyContinuous = BackupPolicyType("Continuous")
+)
+
+// AccountBackupPolicy is the backup configuration of the account. This is read-only metadata.
+type AccountBackupPolicy struct {
+	// Type is either Periodic or Continuous
+	Type BackupPolicyType `json:"type"`
+	// PeriodicModeProperties is set when the Type is Periodic
+	PeriodicModeProperties *PeriodicBackupProperties `json:"periodicModeProperties,omitempty"`
+	// ContinuousModeProperties is set when the Type is Continuous
+	ContinuousModeProperties *ContinuousBackupProperties `json:"continuousModeProperties,omitempty"`
+}
+
+// PeriodicBackupProperties are the settings of a Periodic backup policy
+type PeriodicBackupProperties struct {
+	// BackupIntervalInMinutes is the interval between backups
+	BackupIntervalInMinutes int `json:"backupIntervalInMinutes,omitempty"`
+	// BackupRetentionIntervalInHours is how long each backup is retained
+	BackupRetentionIntervalInHours int `json:"backupRetentionIntervalInHours,omitempty"`
+	// BackupStorageRedundancy is the redundancy of the backup storage, such as "Geo", "Local" or "Zone"
+	BackupStorageRedundancy string `json:"backupStorageRedundancy,omitempty"`
+}
+
+// ContinuousBackupProperties are the settings of a Continuous backup policy
+type ContinuousBackupProperties struct {
+	// Tier is the continuous backup tier, such as "Continuous7Days" or "Continuous30Days"
+	Tier string `json:"tier,omitempty"`
+}
+
+// GetAccountRaw retrieves the raw database account resource
+func (c *Client) GetAccountRaw(ctx context.Context, opts RequestOptions) ([]byte, *ResponseMetadata, error) {
+	return c.GetResource(ctx, ClientRequest{
+		Path:         "/",
+		ResourceType: ResourceType(""),
+		ResourceLink: "",
+		Options:      opts,
+	})
+}
+
+// GetAccount retrieves the AccountProperties of the database account
+func (c *Client) GetAccount(ctx context.Context, opts RequestOptions) (*AccountProperties, *ResponseMetadata, error) {
+	body, meta, err := c.GetAccountRaw(ctx, opts)
+	if err != nil {
+		return nil, meta, err
+	}
+	var account AccountProperties
+	if err = json.Unmarshal(body, &account); err != nil {
+		return nil, meta, err
+	}
+	return &account, meta, nil
+}
+
+// consistencyStrength ranks the consistency levels from weakest (Eventual) to strongest (Strong)
+// Both the request header names (such as Bounded) and the account policy names (such as BoundedStaleness) are accepted.
+func consistencyStrength(level string) (int, bool) {
+	switch strings.ToLower(level) {
+	case "eventual":
+		return 0, true
+	case "consistentprefix":
+		return 1, true
+	case "session":
+		return 2, true
+	case "bounded", "boundedstaleness":
+		return 3, true
+	case "strong":
+		return 4, true
+	}
+	return 0, false
+}
+
+// ValidateConsistency checks that the requested consistency level is not stronger than the account's default consistency level
+// Requests may only relax the consistency configured on the account; a stronger level is rejected by the server.
+// Bounded staleness is configured on the account, so requests cannot set its parameters.
+func (a *AccountProperties) ValidateConsistency(requested ConsistencyLevel) error {
+	if a.UserConsistencyPolicy == nil {
+		return Error("interstellar: account does not have a consistency policy")
+	}
+	def := a.UserConsistencyPolicy.DefaultConsistencyLevel
+	max, ok := consistencyStrength(def)
+	if !ok {
+		return errors.Errorf("interstellar: unknown account consistency level '%s'", def)
+	}
+	req, ok := consistencyStrength(string(requested))
+	if !ok {
+		return errors.Errorf("interstellar: unknown consistency level '%s'", requested)
+	}
+	if req > max {
+		return errors.Errorf("interstellar: consistency level '%s' is stronger than the account default '%s'", requested, def)
+	}
+	return nil
+}
+
+// ValidateConsistency gets the account with GetAccount, and checks that the requested consistency level is not stronger than the account's default
+// See AccountProperties.ValidateConsistency
+func (c *Client) ValidateConsistency(ctx context.Context, requested ConsistencyLevel) error {
+	account, _, err := c.GetAccount(ctx, nil)
+	if err != nil {
+		return err
+	}
+	return account.ValidateConsistency(requested)
+}
+
+// WriteEndpoint returns the endpoint of the named region (such as "West US") in the account's WritableLocations
+// Region names are matched case-insensitively. Writes can only be pinned to a region other than the primary (first) write region
+// when the account has EnableMultipleWriteLocations; otherwise, the account only lists the primary region as writable.
+func (a *AccountProperties) WriteEndpoint(region string) (string, error) {
+	for _, loc := range a.WritableLocations {
+		if strings.EqualFold(loc.Name, region) {
+			return loc.DatabaseAccountEndpoint, nil
+		}
+	}
+	return "", errors.Errorf("interstellar: region '%s' is not a write region of account '%s'", region, a.ID)
+}
@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package interstellar_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/jet/go-interstellar"
+	"github.com/jet/go-interstellar/internal/testutil"
+	"github.com/jet/go-interstellar/internal/testutil/deep"
+)
+
+func TestGetAccountBackupPolicy(t *testing.T) {
+	testdata := testutil.ReadFileBytes(t, filepath.Join("./testdata", "accounts.json"))
+	var accounts []json.RawMessage
+	if err := json.Unmarshal(testdata, &accounts); err != nil {
+		t.Fatal(err)
+	}
+	expected := []*interstellar.AccountBackupPolicy{
+		{
+			Type: interstellar.BackupPolicyPeriodic,
+			PeriodicModeProperties: &interstellar.PeriodicBackupProperties{
+				BackupIntervalInMinutes:        240,
+				BackupRetentionIntervalInHours: 8,
+				BackupStorageRedundancy:        "Geo",
+			},
+		},
+		{
+			Type: interstellar.BackupPolicyContinuous,
+			ContinuousModeProperties: &interstellar.ContinuousBackupProperties{
+				Tier: "Continuous30Days",
+			},
+		},
+	}
+	for i, account := range accounts {
+		client := testutil.NewStubClient(testutil.NewFakeRequester().On(http.MethodGet, "/", testutil.Page(string(account), "")))
+		props, _, err := client.GetAccount(context.Background(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := deep.Equal(expected[i], props.BackupPolicy); diff != nil {
+			t.Errorf("%s: %v", props.ID, diff)
+		}
+	}
+}
+
+func TestValidateConsistency(t *testing.T) {
+	examples := []struct {
+		account   string
+		requested interstellar.ConsistencyLevel
+		valid     bool
+	}{
+		{account: "Session", requested: interstellar.ConsistencyStrong, valid: false},
+		{account: "Session", requested: interstellar.ConsistencyBounded, valid: false},
+		{account: "Session", requested: interstellar.ConsistencySession, valid: true},
+		{account: "Session", requested: interstellar.ConsistencyEventual, valid: true},
+		{account: "BoundedStaleness", requested: interstellar.ConsistencyBounded, valid: true},
+		{account: "BoundedStaleness", requested: interstellar.ConsistencyStrong, valid: false},
+		{account: "Strong", requested: interstellar.ConsistencyStrong, valid: true},
+		{account: "Eventual", requested: interstellar.ConsistencySession, valid: false},
+		{account: "Strong", requested: interstellar.ConsistencyLevel("Linearizable"), valid: false},
+	}
+	for _, ex := range examples {
+		client := testutil.NewStubClient(testutil.NewFakeRequester().
+			On(http.MethodGet, "/", testutil.Page(`{"id":"test","userConsistencyPolicy":{"defaultConsistencyLevel":"`+ex.account+`"}}`, "")))
+		err := client.ValidateConsistency(context.Background(), ex.requested)
+		if (err == nil) != ex.valid {
+			t.Errorf("account %s, requested %s: expected valid=%v, got %v", ex.account, ex.requested, ex.valid, err)
+		}
+	}
+}
@@ -25,6 +25,8 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 const (
@@ -51,24 +53,71 @@ type ConnectionString struct {
 //
 //     AccountEndpoint=https://accountname.documents.azure.com:443/;AccountKey=BASE64KEY;
 //
+// Keys are matched case-insensitively, and unknown keys (such as ApiKind) are ignored.
 func ParseConnectionString(connectionString string) (ConnectionString, error) {
 	var cs ConnectionString
 	for _, cmp := range strings.Split(connectionString, ";") {
+		cmp = strings.TrimSpace(cmp)
+		if cmp == "" {
+			continue
+		}
 		kv := strings.SplitN(cmp, "=", 2)
-		switch kv[0] {
-		case "AccountEndpoint":
-			cs.Endpoint = kv[1]
-		case "AccountKey":
-			kb, err := ParseMasterKey(kv[1])
+		if len(kv) != 2 {
+			return cs, errors.Errorf("interstellar: invalid connection string component '%s': expected key=value", kv[0])
+		}
+		key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		switch strings.ToLower(key) {
+		case "accountendpoint":
+			cs.Endpoint = value
+		case "accountkey":
+			if value == RedactedValue {
+				// REDACTED is valid base64, so it would otherwise be accepted as a key which fails every request with 401 Unauthorized
+				return cs, errors.New("interstellar: AccountKey in connection string is redacted; use ConnectionString.Secret to format a connection string which can be parsed")
+			}
+			kb, err := ParseMasterKey(value)
 			if err != nil {
-				return cs, err
+				return cs, errors.Wrap(err, "interstellar: invalid AccountKey in connection string")
 			}
 			cs.AccountKey = MasterKey(kb)
 		}
 	}
+	if cs.Endpoint == "" {
+		return cs, errors.New("interstellar: connection string is missing AccountEndpoint")
+	}
+	if len(cs.AccountKey) == 0 {
+		return cs, errors.New("interstellar: connection string is missing AccountKey")
+	}
 	return cs, nil
 }
 
+// NewConnectionString creates a ConnectionString from the account endpoint and master key
+// This is useful when the endpoint and key are supplied separately, such as in different environment variables.
+func NewConnectionString(endpoint string, key MasterKey) ConnectionString {
+	return ConnectionString{
+		Endpoint:   endpoint,
+		AccountKey: key,
+	}
+}
+
+// String formats the connection string with the account key redacted, so it is safe to log:
+//
+//     AccountEndpoint=https://accountname.documents.azure.com:443/;AccountKey=REDACTED;
+//
+// The output cannot be parsed back with ParseConnectionString, which rejects the redacted key;
+// use Secret to format the connection string with the account key.
+func (cs ConnectionString) String() string {
+	return fmt.Sprintf("AccountEndpoint=%s;AccountKey=%s;", cs.Endpoint, RedactedValue)
+}
+
+// Secret formats the connection string in the same format that ParseConnectionString accepts:
+//
+//     AccountEndpoint=https://accountname.documents.azure.com:443/;AccountKey=BASE64KEY;
+//
+// Note: The result contains the account key, so it must be treated as a secret.
+func (cs ConnectionString) Secret() string {
+	return fmt.Sprintf("AccountEndpoint=%s;AccountKey=%s;", cs.Endpoint, base64.StdEncoding.EncodeToString(cs.AccountKey))
+}
+
 // ParseMasterKey parses a base-64 encoded shared access key
 func ParseMasterKey(key string) (MasterKey, error) {
 	kb, err := base64.StdEncoding.DecodeString(key)
@@ -115,17 +164,75 @@ const (
 	ResourcePermissions ResourceType = "permissions"
 	// ResourceOffers is the resource type of an Offer
 	ResourceOffers ResourceType = "offers"
+	// ResourcePartitionKeyRanges is the resource type of a Partition Key Range
+	ResourcePartitionKeyRanges ResourceType = "pkranges"
 )
 
+// Signer signs the string-to-sign of a request, and returns the base-64 encoded HMAC-SHA256 signature
+// This allows the master key to be kept out of memory, such as in an HSM or Key Vault, and used to sign requests remotely.
+type Signer interface {
+	Sign(message string) (string, error)
+}
+
+// SignerFunc is a function which implements the Signer interface
+type SignerFunc func(message string) (string, error)
+
+// Sign calls the function
+func (f SignerFunc) Sign(message string) (string, error) {
+	return f(message)
+}
+
+// SignatureAuthorizer is an Authorizer which signs requests with the master key token, using the Signer to compute the signature
+// For example, to sign with a key held by a remote service:
+//
+//     client.Authorizer = &interstellar.SignatureAuthorizer{
+//         Signer: interstellar.SignerFunc(func(message string) (string, error) {
+//             return vault.SignHMACSHA256(ctx, keyName, message)
+//         }),
+//     }
+//
+// Now is the clock used to date the request; if nil, time.Now is used.
+type SignatureAuthorizer struct {
+	Signer Signer
+	Now    func() time.Time
+}
+
+// Authorize implements the authorization header for Microsoft Azure Storage services
+// See MasterKey.Authorize
+func (a *SignatureAuthorizer) Authorize(r *http.Request, resourceType ResourceType, resourceLink string) (*http.Request, error) {
+	now := time.Now
+	if a.Now != nil {
+		now = a.Now
+	}
+	if err := authorize(r, resourceType, resourceLink, a.Signer, now()); err != nil {
+		return nil, errors.Wrap(err, "interstellar: could not sign request")
+	}
+	return r, nil
+}
+
 // Authorize implements the authorization header for Microsoft Azure Storage services
 // See https://docs.microsoft.com/en-us/rest/api/cosmos-db/access-control-on-cosmosdb-resources#constructkeytoken
 // for implementation details.
 // This implementation assumes the latest version of the API is 2017-04-17
 func (k MasterKey) Authorize(r *http.Request, resourceType ResourceType, resourceLink string) (*http.Request, error) {
+	return k.AuthorizeAt(r, resourceType, resourceLink, time.Now())
+}
+
+// AuthorizeAt is the same as Authorize, but signs the request as if it were made at time t
+// This makes the x-ms-date header and the signature reproducible, which is useful for testing.
+func (k MasterKey) AuthorizeAt(r *http.Request, resourceType ResourceType, resourceLink string, t time.Time) (*http.Request, error) {
 	if k == nil {
 		return r, nil
 	}
-	date := time.Now().UTC().Format(http.TimeFormat)
+	if err := authorize(r, resourceType, resourceLink, k.Signer(), t); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// authorize sets the master key token on the request, signed by the signer and dated at time t
+func authorize(r *http.Request, resourceType ResourceType, resourceLink string, signer Signer, t time.Time) error {
+	date := t.UTC().Format(http.TimeFormat)
 	cs := strings.Join([]string{
 		strings.ToLower(r.Method),
 		resourceType.String(),
@@ -133,14 +240,17 @@ func (k MasterKey) Authorize(r *http.Request, resourceType ResourceType, resourc
 		strings.ToLower(date),
 		"", "",
 	}, "\n")
-	sig := k.Sign(cs)
+	sig, err := signer.Sign(cs)
+	if err != nil {
+		return err
+	}
 	token := url.QueryEscape(fmt.Sprintf("type=%s&ver=%s&sig=%s", MasterTokenAuthType, TokenVersion, sig))
 	r.Header.Set(HeaderAuthorization, token)
 	if r.Header.Get(HeaderMSAPIVersion) == "" {
 		r.Header.Set(HeaderMSAPIVersion, APIVersion)
 	}
 	r.Header.Set(HeaderMSDate, date)
-	return r, nil
+	return nil
 }
 
 // Sign will sign the message with HMAC-SHA256
@@ -150,3 +260,10 @@ func (k MasterKey) Sign(message string) string {
 	h.Write([]byte(message))
 	return base64.StdEncoding.EncodeToString(h.Sum(nil))
 }
+
+// Signer returns a Signer which signs messages in-memory with the key
+func (k MasterKey) Signer() Signer {
+	return SignerFunc(func(message string) (string, error) {
+		return k.Sign(message), nil
+	})
+}
@@ -0,0 +1,213 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package interstellar_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jet/go-interstellar"
+)
+
+const emulatorKey = "C2y6yDjf5/R+ob0N8A7Cgv30VRDJIWEHLM+4QDU5DE2nQ9nDuVTqobD4b8mGGyPMbIZnqyMsEcaGQy67XIw/Jw=="
+
+func TestConnectionStringRoundTrip(t *testing.T) {
+	key, err := interstellar.ParseMasterKey(emulatorKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := interstellar.NewConnectionString("https://localhost:8081/", key)
+	expected := "AccountEndpoint=https://localhost:8081/;AccountKey=" + emulatorKey + ";"
+	if cs.Secret() != expected {
+		t.Fatal("expected the secret connection string to contain the account key")
+	}
+	parsed, err := interstellar.ParseConnectionString(cs.Secret())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Endpoint != cs.Endpoint || !bytes.Equal(parsed.AccountKey, cs.AccountKey) {
+		t.Fatalf("connection string did not round trip: %s", parsed)
+	}
+}
+
+func TestConnectionStringRedacted(t *testing.T) {
+	key, err := interstellar.ParseMasterKey(emulatorKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := interstellar.NewConnectionString("https://localhost:8081/", key)
+	expected := "AccountEndpoint=https://localhost:8081/;AccountKey=REDACTED;"
+	for _, s := range []string{cs.String(), fmt.Sprintf("%v", cs), fmt.Sprintf("%+v", struct{ CS interstellar.ConnectionString }{cs})} {
+		if strings.Contains(s, emulatorKey) {
+			t.Fatal("expected the account key to be redacted")
+		}
+	}
+	if cs.String() != expected {
+		t.Errorf("expected '%s', got '%s'", expected, cs.String())
+	}
+	if _, err := interstellar.ParseConnectionString(cs.String()); err == nil || !strings.Contains(err.Error(), "redacted") {
+		t.Errorf("expected the redacted connection string to be rejected, got %v", err)
+	}
+}
+
+func TestParseConnectionString(t *testing.T) {
+	tests := []struct {
+		name     string
+		cstring  string
+		endpoint string
+		err      bool
+	}{
+		{
+			name:     "canonical",
+			cstring:  "AccountEndpoint=https://localhost:8081/;AccountKey=" + emulatorKey,
+			endpoint: "https://localhost:8081/",
+		},
+		{
+			name:     "trailing semicolons",
+			cstring:  "AccountEndpoint=https://localhost:8081/;AccountKey=" + emulatorKey + ";;",
+			endpoint: "https://localhost:8081/",
+		},
+		{
+			name:     "whitespace",
+			cstring:  "  AccountEndpoint = https://localhost:8081/ ;\n AccountKey= " + emulatorKey + " ;\n",
+			endpoint: "https://localhost:8081/",
+		},
+		{
+			name:    "missing equals",
+			cstring: "AccountEndpoint=https://localhost:8081/;AccountKey",
+			err:     true,
+		},
+		{
+			name:    "missing endpoint",
+			cstring: "AccountKey=" + emulatorKey,
+			err:     true,
+		},
+		{
+			name:    "missing key",
+			cstring: "AccountEndpoint=https://localhost:8081/",
+			err:     true,
+		},
+		{
+			name:    "invalid key",
+			cstring: "AccountEndpoint=https://localhost:8081/;AccountKey=not base64!",
+			err:     true,
+		},
+		{
+			name:     "case insensitive keys",
+			cstring:  "accountendpoint=https://localhost:8081/;ACCOUNTKEY=" + emulatorKey,
+			endpoint: "https://localhost:8081/",
+		},
+		{
+			name:     "extra keys",
+			cstring:  "AccountEndpoint=https://localhost:8081/;AccountKey=" + emulatorKey + ";ApiKind=Sql;Database=db1",
+			endpoint: "https://localhost:8081/",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cs, err := interstellar.ParseConnectionString(test.cstring)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cs.Endpoint != test.endpoint {
+				t.Errorf("expected endpoint '%s', got '%s'", test.endpoint, cs.Endpoint)
+			}
+			if len(cs.AccountKey) == 0 {
+				t.Errorf("expected account key to be parsed")
+			}
+		})
+	}
+}
+
+func TestSignatureAuthorizer(t *testing.T) {
+	key, err := interstellar.ParseMasterKey(emulatorKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var signed string
+	auth := &interstellar.SignatureAuthorizer{
+		Signer: interstellar.SignerFunc(func(message string) (string, error) {
+			signed = message
+			return key.Sign(message), nil
+		}),
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://localhost:8081/dbs/db1", nil)
+	if req, err = auth.Authorize(req, interstellar.ResourceDatabases, "dbs/db1"); err != nil {
+		t.Fatal(err)
+	}
+	date := req.Header.Get(interstellar.HeaderMSDate)
+	expected := "get\ndbs\ndbs/db1\n" + strings.ToLower(date) + "\n\n"
+	if signed != expected {
+		t.Errorf("unexpected string to sign: %q", signed)
+	}
+	token := url.QueryEscape("type=master&ver=1.0&sig=" + key.Sign(expected))
+	if actual := req.Header.Get(interstellar.HeaderAuthorization); actual != token {
+		t.Errorf("expected token %s, got %s", token, actual)
+	}
+
+	auth.Signer = interstellar.SignerFunc(func(message string) (string, error) {
+		return "", errors.New("vault unavailable")
+	})
+	req, _ = http.NewRequest(http.MethodGet, "https://localhost:8081/dbs/db1", nil)
+	if _, err = auth.Authorize(req, interstellar.ResourceDatabases, "dbs/db1"); err == nil {
+		t.Error("expected the signer's error")
+	}
+}
+
+func TestMasterKeyAuthorizeAt(t *testing.T) {
+	key, err := interstellar.ParseMasterKey(emulatorKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2019, time.March, 4, 5, 6, 7, 0, time.UTC)
+	req, _ := http.NewRequest(http.MethodGet, "https://localhost:8081/dbs/db1", nil)
+	if req, err = key.AuthorizeAt(req, interstellar.ResourceDatabases, "dbs/db1", at); err != nil {
+		t.Fatal(err)
+	}
+	if date := req.Header.Get(interstellar.HeaderMSDate); date != "Mon, 04 Mar 2019 05:06:07 GMT" {
+		t.Errorf("unexpected date %s", date)
+	}
+	token := url.QueryEscape("type=master&ver=1.0&sig=" + key.Sign("get\ndbs\ndbs/db1\nmon, 04 mar 2019 05:06:07 gmt\n\n"))
+	if actual := req.Header.Get(interstellar.HeaderAuthorization); actual != token {
+		t.Errorf("expected token %s, got %s", token, actual)
+	}
+
+	// the same clock gives the same signature through a SignatureAuthorizer
+	auth := &interstellar.SignatureAuthorizer{
+		Signer: key.Signer(),
+		Now:    func() time.Time { return at },
+	}
+	req2, _ := http.NewRequest(http.MethodGet, "https://localhost:8081/dbs/db1", nil)
+	if req2, err = auth.Authorize(req2, interstellar.ResourceDatabases, "dbs/db1"); err != nil {
+		t.Fatal(err)
+	}
+	if actual := req2.Header.Get(interstellar.HeaderAuthorization); actual != token {
+		t.Errorf("expected token %s, got %s", token, actual)
+	}
+}
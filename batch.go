@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+
+package interstellar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// BatchReadConcurrency is the number of point reads BatchReadDocuments runs concurrently
+const BatchReadConcurrency = 8
+
+// DocumentRef identifies a single document in any database and collection of the account
+type DocumentRef struct {
+	Database   string
+	Collection string
+	ID         string
+	// PartitionKey is the partition key value of the document, or empty for a non-partitioned collection
+	// It is a single value (rather than a []string) so that a DocumentRef can be used as a map key.
+	PartitionKey string
+}
+
+// less orders references by database, collection, ID, and partition key
+func (r DocumentRef) less(o DocumentRef) bool {
+	if r.Database != o.Database {
+		return r.Database < o.Database
+	}
+	if r.Collection != o.Collection {
+		return r.Collection < o.Collection
+	}
+	if r.ID != o.ID {
+		return r.ID < o.ID
+	}
+	return r.PartitionKey < o.PartitionKey
+}
+
+func (r DocumentRef) client(c *Client) *DocumentClient {
+	var pk []string
+	if r.PartitionKey != "" {
+		pk = []string{r.PartitionKey}
+	}
+	return c.Document(r.Database, r.Collection, r.ID, pk)
+}
+
+// BatchReadError is returned by BatchReadDocuments with the error of each document which could not be read
+type BatchReadError map[DocumentRef]error
+
+// Error reports the number of documents which could not be read, and the error of the first one in the order of their references
+func (e BatchReadError) Error() string {
+	var first *DocumentRef
+	for ref := range e {
+		if ref := ref; first == nil || ref.less(*first) {
+			first = &ref
+		}
+	}
+	if first == nil {
+		return "interstellar: no documents failed to be read"
+	}
+	return fmt.Sprintf("interstellar: %d document(s) could not be read, including '%s' in %s/%s: %v", len(e), first.ID, first.Database, first.Collection, e[*first])
+}
+
+// BatchReadDocuments reads each of the documents concurrently, and returns the raw documents by reference
+// The documents may be in different databases and collections; up to BatchReadConcurrency documents are read at once.
+// Documents which do not exist are left out of the results.
+// If any other reads fail, the documents which were read are returned with a BatchReadError.
+// If the context is cancelled, the documents read so far are returned with the context's error.
+func (c *Client) BatchReadDocuments(ctx context.Context, refs []DocumentRef) (map[DocumentRef]json.RawMessage, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[DocumentRef]json.RawMessage, len(refs))
+		errs    = make(BatchReadError)
+		jobs    = make(chan DocumentRef)
+	)
+	workers := BatchReadConcurrency
+	if len(refs) < workers {
+		workers = len(refs)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ref := range jobs {
+				body, _, err := ref.client(c).GetRaw(ctx, nil)
+				mu.Lock()
+				switch err {
+				case nil:
+					results[ref] = json.RawMessage(body)
+				case ErrResourceNotFound:
+				default:
+					errs[ref] = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	seen := make(map[DocumentRef]bool, len(refs))
+feed:
+	for _, ref := range refs {
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		select {
+		case jobs <- ref:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+	if len(errs) > 0 {
+		return results, errs
+	}
+	return results, nil
+}
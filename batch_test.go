@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+
+package interstellar_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jet/go-interstellar"
+	"github.com/jet/go-interstellar/internal/testutil"
+)
+
+func TestBatchReadDocuments(t *testing.T) {
+	var mu sync.Mutex
+	pks := make(map[string]string)
+	client := testutil.NewStubClient(testutil.RequesterFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		pks[req.URL.Path] = req.Header.Get(interstellar.HeaderDocDBPartitionKey)
+		mu.Unlock()
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/missing"):
+			return testutil.NewResponse(req, http.StatusNotFound, nil, `{"code":"NotFound"}`), nil
+		case strings.HasSuffix(req.URL.Path, "/broken"):
+			return testutil.NewResponse(req, http.StatusInternalServerError, nil, `{"code":"InternalServerError"}`), nil
+		}
+		id := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+		return testutil.NewResponse(req, http.StatusOK, nil, `{"id":"`+id+`"}`), nil
+	}))
+	a := interstellar.DocumentRef{Database: "db1", Collection: "col1", ID: "a"}
+	b := interstellar.DocumentRef{Database: "db2", Collection: "col2", ID: "b", PartitionKey: "pk"}
+	missing := interstellar.DocumentRef{Database: "db1", Collection: "col1", ID: "missing"}
+	results, err := client.BatchReadDocuments(context.Background(), []interstellar.DocumentRef{a, b, missing, a})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || string(results[a]) != `{"id":"a"}` || string(results[b]) != `{"id":"b"}` {
+		t.Errorf("unexpected results: %v", results)
+	}
+	if pks["/dbs/db2/colls/col2/docs/b"] != `["pk"]` || pks["/dbs/db1/colls/col1/docs/a"] != "" {
+		t.Errorf("unexpected partition keys: %v", pks)
+	}
+
+	broken := interstellar.DocumentRef{Database: "db1", Collection: "col1", ID: "broken"}
+	results, err = client.BatchReadDocuments(context.Background(), []interstellar.DocumentRef{a, broken})
+	berr, ok := err.(interstellar.BatchReadError)
+	if !ok {
+		t.Fatalf("expected a BatchReadError, got %v", err)
+	}
+	if len(berr) != 1 || berr[broken] == nil {
+		t.Errorf("unexpected errors: %v", berr)
+	}
+	if len(results) != 1 || results[a] == nil {
+		t.Errorf("expected the successful reads to be returned: %v", results)
+	}
+}
+
+func TestBatchReadErrorMessage(t *testing.T) {
+	berr := interstellar.BatchReadError{
+		{Database: "db2", Collection: "col1", ID: "a"}: interstellar.ErrResourceNotFound,
+		{Database: "db1", Collection: "col2", ID: "a"}: interstellar.ErrPreconditionFailed,
+		{Database: "db1", Collection: "col1", ID: "b"}: interstellar.ErrResourceConflict,
+		{Database: "db1", Collection: "col1", ID: "c"}: interstellar.ErrCircuitOpen,
+	}
+	expected := "interstellar: 4 document(s) could not be read, including 'b' in db1/col1: " + interstellar.ErrResourceConflict.Error()
+	for i := 0; i < 10; i++ {
+		if actual := berr.Error(); actual != expected {
+			t.Fatalf("expected %s, got %s", expected, actual)
+		}
+	}
+	if actual := (interstellar.BatchReadError{}).Error(); actual != "interstellar: no documents failed to be read" {
+		t.Errorf("unexpected message for no errors: %s", actual)
+	}
+}
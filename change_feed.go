@@ -0,0 +1,218 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package interstellar
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// SubStatusPartitionKeyRangeGone is the sub-status of a 410 Gone response for a partition key range which was split
+	SubStatusPartitionKeyRangeGone = "1002"
+	// SubStatusCompletingSplit is the sub-status of a 410 Gone response for a partition key range which is being split
+	SubStatusCompletingSplit = "1007"
+)
+
+// errPartitionKeyRangeGone is returned by readChangeFeedRange when the partition key range was split
+const errPartitionKeyRangeGone = Error("interstellar: partition key range is gone")
+
+// changeFeedRange is the progress of reading the change feed of a partition key range
+type changeFeedRange struct {
+	id   string
+	etag string
+}
+
+// ReadChangeFeedSince reads the changes to documents in the collection which were made at or after the start time
+// The change feed of each partition key range is read in turn, starting from the start time (using If-Modified-Since),
+// and continuing from the ETag of the previous page (using If-None-Match), until the range reports no further changes.
+// Each page of changed documents is given to the pagination function; only the latest version of each document is included.
+//
+// This is useful for incremental jobs which run on a schedule, and know the time of their last successful run:
+//
+//     err := cc.ReadChangeFeedSince(ctx, lastRun, func(resList []json.RawMessage, meta interstellar.ResponseMetadata) (bool, error) {
+//         // process changes
+//         return true, nil
+//     })
+//
+// If a partition key range is split while it is being read (410 Gone), the partition key ranges are listed again,
+// and the child ranges of the split range are read instead, continuing from the ETag the split range had reached.
+//
+// If the pagination function returns (false, nil), reading stops without error.
+func (c *CollectionClient) ReadChangeFeedSince(ctx context.Context, start time.Time, fn PaginateRawResources) error {
+	fn = stripSystemPropertiesFrom(c.StripSystemProperties, fn)
+	ranges, err := c.listAllPartitionKeyRanges(ctx)
+	if err != nil {
+		return err
+	}
+	queue := make([]changeFeedRange, len(ranges))
+	for i, pkr := range ranges {
+		queue[i] = changeFeedRange{id: pkr.ID}
+	}
+	for len(queue) > 0 {
+		r := queue[0]
+		queue = queue[1:]
+		ok, etag, err := c.readChangeFeedRange(ctx, r, start, fn)
+		if err == errPartitionKeyRangeGone {
+			children, err := c.childPartitionKeyRanges(ctx, r.id)
+			if err != nil {
+				return err
+			}
+			for _, child := range children {
+				queue = append(queue, changeFeedRange{id: child, etag: etag})
+			}
+			continue
+		}
+		if err == ErrResourceNotFound {
+			// the collection was deleted; return the sentinel like every other read
+			return err
+		}
+		if err != nil {
+			return errors.Wrapf(err, "interstellar: could not read change feed of partition key range '%s'", r.id)
+		}
+		if !ok {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (c *CollectionClient) listAllPartitionKeyRanges(ctx context.Context) ([]PartitionKeyRangeResource, error) {
+	var ranges []PartitionKeyRangeResource
+	err := c.ListPartitionKeyRanges(ctx, nil, func(resList []PartitionKeyRangeResource, meta ResponseMetadata) (bool, error) {
+		ranges = append(ranges, resList...)
+		return true, nil
+	})
+	return ranges, err
+}
+
+// childPartitionKeyRanges lists the IDs of the partition key ranges which were split from the parent
+func (c *CollectionClient) childPartitionKeyRanges(ctx context.Context, parent string) ([]string, error) {
+	ranges, err := c.listAllPartitionKeyRanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var children []string
+	for _, pkr := range ranges {
+		for _, p := range pkr.Parents {
+			if p == parent {
+				children = append(children, pkr.ID)
+				break
+			}
+		}
+	}
+	if len(children) == 0 {
+		return nil, errors.Errorf("interstellar: partition key range '%s' is gone, but no child ranges were found", parent)
+	}
+	return children, nil
+}
+
+// readChangeFeedRange reads the change feed of a single partition key range, until no further changes are reported
+// Returns false if the pagination function stopped reading, along with the ETag reached.
+// If the range was split, errPartitionKeyRangeGone is returned. While the split is completing, the range is retried with the same backoff as throttled pages.
+func (c *CollectionClient) readChangeFeedRange(ctx context.Context, r changeFeedRange, start time.Time, fn PaginateRawResources) (bool, string, error) {
+	rl := c.ResourceLink()
+	opts := &CommonRequestOptions{
+		ChangeFeed:                    true,
+		DocumentDBPartitionKeyRangeID: r.id,
+		IfModifiedSince:               start,
+		IfNoneMatch:                   r.etag,
+	}
+	completing := 0
+	for {
+		if ctx != nil {
+			select {
+			case <-ctx.Done():
+				return false, opts.IfNoneMatch, ctx.Err()
+			default:
+			}
+		}
+		request := ClientRequest{
+			Method:       http.MethodGet,
+			Path:         fmt.Sprintf("/%s/docs", rl),
+			ResourceLink: rl,
+			ResourceType: ResourceDocuments,
+			Options:      opts,
+		}
+		req, err := c.Client.NewHTTPRequest(ctx, request)
+		if err != nil {
+			return false, opts.IfNoneMatch, err
+		}
+		resp, err := c.Client.do(req, request)
+		if err != nil {
+			return false, opts.IfNoneMatch, err
+		}
+		switch resp.StatusCode {
+		case http.StatusOK:
+			completing = 0
+		case http.StatusNotModified:
+			// no further changes
+			resp.Body.Close()
+			return true, opts.IfNoneMatch, nil
+		case http.StatusNotFound:
+			resp.Body.Close()
+			return false, opts.IfNoneMatch, ErrResourceNotFound
+		case http.StatusGone:
+			switch resp.Header.Get(HeaderSubStatus) {
+			case SubStatusPartitionKeyRangeGone:
+				resp.Body.Close()
+				return false, opts.IfNoneMatch, errPartitionKeyRangeGone
+			case SubStatusCompletingSplit:
+				if completing < c.Client.pageThrottleRetries() {
+					// the child ranges may not be listed until the split completes, so retry the same range
+					wait := c.Client.throttleBackoff(completing, GetResponseMetadata(resp).RetryAfterMS)
+					resp.Body.Close()
+					completing++
+					if err = sleepContext(ctx, wait); err != nil {
+						return false, opts.IfNoneMatch, err
+					}
+					continue
+				}
+			}
+			return false, opts.IfNoneMatch, newCosmosError(resp)
+		default:
+			return false, opts.IfNoneMatch, newCosmosError(resp)
+		}
+		meta := GetResponseMetadata(resp)
+		results, err := ParseArrayFromResponse(resp.Body, "Documents")
+		resp.Body.Close()
+		if errors.Cause(err) == ErrResponseTooLarge {
+			return false, opts.IfNoneMatch, ErrResponseTooLarge
+		}
+		if err != nil {
+			return false, opts.IfNoneMatch, errors.Wrap(err, "interstellar: malformed change feed response")
+		}
+		if len(results) == 0 {
+			return true, opts.IfNoneMatch, nil
+		}
+		ok, err := fn(results, meta)
+		if meta.ETag != "" {
+			// continue from where this page left off
+			opts.IfNoneMatch = meta.ETag
+		}
+		if err != nil || !ok {
+			return false, opts.IfNoneMatch, err
+		}
+		if meta.ETag == "" {
+			return true, opts.IfNoneMatch, nil
+		}
+	}
+}
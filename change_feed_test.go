@@ -0,0 +1,193 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package interstellar_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jet/go-interstellar"
+	"github.com/jet/go-interstellar/internal/testutil"
+)
+
+func TestReadChangeFeedSince(t *testing.T) {
+	start := time.Date(2019, time.March, 4, 5, 6, 7, 0, time.UTC)
+	client := testutil.NewStubClient(testutil.RequesterFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/dbs/db1/colls/col1/pkranges":
+			return testutil.NewResponse(req, http.StatusOK, nil, `{"PartitionKeyRanges":[{"id":"0"},{"id":"1"}]}`), nil
+		case "/dbs/db1/colls/col1/docs":
+			if req.Header.Get(interstellar.HeaderAIM) != "Incremental feed" {
+				t.Errorf("expected a change feed request")
+			}
+			rangeID := req.Header.Get(interstellar.HeaderDocDBPartitionKeyRangeID)
+			switch req.Header.Get(interstellar.HeaderIfNoneMatch) {
+			case "":
+				if since := req.Header.Get(interstellar.HeaderIfModifiedSince); since != start.Format(http.TimeFormat) {
+					t.Errorf("unexpected If-Modified-Since: %s", since)
+				}
+				hdr := make(http.Header)
+				hdr.Set(interstellar.HeaderETag, `"`+rangeID+`-1"`)
+				return testutil.NewResponse(req, http.StatusOK, hdr, `{"Documents":[{"id":"doc`+rangeID+`"}]}`), nil
+			case `"` + rangeID + `-1"`:
+				return testutil.NewResponse(req, http.StatusNotModified, nil, ``), nil
+			}
+		}
+		t.Fatalf("unexpected request %s %s (%v)", req.Method, req.URL.Path, req.Header)
+		return nil, nil
+	}))
+	cc := client.WithDatabase("db1").WithCollection("col1")
+	var ids []string
+	err := cc.ReadChangeFeedSince(context.Background(), start, func(resList []json.RawMessage, meta interstellar.ResponseMetadata) (bool, error) {
+		for _, raw := range resList {
+			var doc interstellar.DocumentProperties
+			if err := json.Unmarshal(raw, &doc); err != nil {
+				return false, err
+			}
+			ids = append(ids, doc.ID)
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != "doc0" || ids[1] != "doc1" {
+		t.Errorf("unexpected changes: %v", ids)
+	}
+}
+
+func TestReadChangeFeedSinceSplit(t *testing.T) {
+	split := false
+	var reads []string
+	client := testutil.NewStubClient(testutil.RequesterFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/dbs/db1/colls/col1/pkranges":
+			if !split {
+				return testutil.NewResponse(req, http.StatusOK, nil, `{"PartitionKeyRanges":[{"id":"0"}]}`), nil
+			}
+			return testutil.NewResponse(req, http.StatusOK, nil, `{"PartitionKeyRanges":[{"id":"1","parents":["0"]},{"id":"2","parents":["0"]}]}`), nil
+		case "/dbs/db1/colls/col1/docs":
+			rangeID := req.Header.Get(interstellar.HeaderDocDBPartitionKeyRangeID)
+			etag := req.Header.Get(interstellar.HeaderIfNoneMatch)
+			reads = append(reads, rangeID+":"+etag)
+			hdr := make(http.Header)
+			switch {
+			case rangeID == "0" && etag == "":
+				hdr.Set(interstellar.HeaderETag, `"10"`)
+				return testutil.NewResponse(req, http.StatusOK, hdr, `{"Documents":[{"id":"a"}]}`), nil
+			case rangeID == "0":
+				// split after the first page
+				split = true
+				hdr.Set(interstellar.HeaderSubStatus, interstellar.SubStatusPartitionKeyRangeGone)
+				return testutil.NewResponse(req, http.StatusGone, hdr, `{"code":"Gone"}`), nil
+			case etag == `"10"`:
+				hdr.Set(interstellar.HeaderETag, `"20"`)
+				return testutil.NewResponse(req, http.StatusOK, hdr, `{"Documents":[{"id":"b`+rangeID+`"}]}`), nil
+			default:
+				return testutil.NewResponse(req, http.StatusNotModified, nil, ``), nil
+			}
+		}
+		t.Fatalf("unexpected request %s %s", req.Method, req.URL.Path)
+		return nil, nil
+	}))
+	var ids []string
+	err := client.WithDatabase("db1").WithCollection("col1").ReadChangeFeedSince(context.Background(), time.Time{}, func(resList []json.RawMessage, meta interstellar.ResponseMetadata) (bool, error) {
+		for _, raw := range resList {
+			var doc interstellar.DocumentProperties
+			if err := json.Unmarshal(raw, &doc); err != nil {
+				return false, err
+			}
+			ids = append(ids, doc.ID)
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3 || ids[0] != "a" || ids[1] != "b1" || ids[2] != "b2" {
+		t.Errorf("unexpected changes: %v", ids)
+	}
+	expected := []string{`0:`, `0:"10"`, `1:"10"`, `1:"20"`, `2:"10"`, `2:"20"`}
+	if len(reads) != len(expected) {
+		t.Fatalf("expected reads %v, got %v", expected, reads)
+	}
+	for i := range expected {
+		if reads[i] != expected[i] {
+			t.Errorf("expected reads %v, got %v", expected, reads)
+			break
+		}
+	}
+}
+
+func TestReadChangeFeedSinceCompletingSplit(t *testing.T) {
+	var reads int
+	client := testutil.NewStubClient(testutil.RequesterFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/dbs/db1/colls/col1/pkranges":
+			// the child ranges are not listed until the split completes
+			return testutil.NewResponse(req, http.StatusOK, nil, `{"PartitionKeyRanges":[{"id":"0"}]}`), nil
+		case "/dbs/db1/colls/col1/docs":
+			reads++
+			hdr := make(http.Header)
+			switch reads {
+			case 1:
+				hdr.Set(interstellar.HeaderSubStatus, interstellar.SubStatusCompletingSplit)
+				hdr.Set(interstellar.HeaderRetryAfterMS, "1")
+				return testutil.NewResponse(req, http.StatusGone, hdr, `{"code":"Gone"}`), nil
+			case 2:
+				hdr.Set(interstellar.HeaderETag, `"10"`)
+				return testutil.NewResponse(req, http.StatusOK, hdr, `{"Documents":[{"id":"a"}]}`), nil
+			default:
+				return testutil.NewResponse(req, http.StatusNotModified, nil, ``), nil
+			}
+		}
+		t.Fatalf("unexpected request %s %s", req.Method, req.URL.Path)
+		return nil, nil
+	}))
+	var ids []string
+	err := client.WithDatabase("db1").WithCollection("col1").ReadChangeFeedSince(context.Background(), time.Time{}, func(resList []json.RawMessage, meta interstellar.ResponseMetadata) (bool, error) {
+		for _, raw := range resList {
+			var doc interstellar.DocumentProperties
+			if err := json.Unmarshal(raw, &doc); err != nil {
+				return false, err
+			}
+			ids = append(ids, doc.ID)
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != "a" || reads != 3 {
+		t.Errorf("expected the range to be retried, got changes %v after %d reads", ids, reads)
+	}
+}
+
+func TestReadChangeFeedSinceNotFound(t *testing.T) {
+	// the change feed of the deleted collection is not found
+	client := testutil.NewStubClient(testutil.NewFakeRequester().
+		On(http.MethodGet, "/dbs/db1/colls/col1/pkranges", testutil.Page(`{"PartitionKeyRanges":[{"id":"0"}]}`, "")))
+	err := client.WithDatabase("db1").WithCollection("col1").ReadChangeFeedSince(context.Background(), time.Time{}, func(resList []json.RawMessage, meta interstellar.ResponseMetadata) (bool, error) {
+		return true, nil
+	})
+	if err != interstellar.ErrResourceNotFound {
+		t.Errorf("expected ErrResourceNotFound for a deleted collection, got %v", err)
+	}
+}
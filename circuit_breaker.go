@@ -0,0 +1,235 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package interstellar
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// ErrCircuitOpen is returned by CircuitBreakerRequester when requests are failing fast, without being sent
+	ErrCircuitOpen = Error("interstellar: circuit breaker is open")
+
+	// DefaultCircuitBreakerWindow is the number of recent responses considered by a CircuitBreakerRequester, if its Window is not set
+	DefaultCircuitBreakerWindow = 20
+	// DefaultCircuitBreakerFailureRatio is the ratio of throttled responses which trips a CircuitBreakerRequester, if its FailureRatio is not set
+	DefaultCircuitBreakerFailureRatio = 0.5
+	// DefaultCircuitBreakerCooldown is how long a CircuitBreakerRequester stays open, if its Cooldown is not set
+	DefaultCircuitBreakerCooldown = 10 * time.Second
+)
+
+// CircuitState is the state of a CircuitBreakerRequester
+type CircuitState int
+
+const (
+	// CircuitClosed sends every request, and records whether the response was throttled
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails every request with ErrCircuitOpen, without sending it, until the cooldown has passed
+	CircuitOpen
+	// CircuitHalfOpen sends a single probe request; the circuit closes if it succeeds, or opens again if it is throttled
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreakerRequester is a Requester which stops sending requests while CosmosDB is persistently throttling or unavailable
+// Retrying blindly under sustained throttling only adds load, so once the ratio of throttled (429) or unavailable (503) responses
+// among the last Window responses reaches FailureRatio, the circuit opens: requests fail fast with ErrCircuitOpen for the Cooldown.
+// After the cooldown, one probe request is sent (half-open); the circuit closes if the probe succeeds, and opens again otherwise.
+//
+// It should wrap the retrying requesters, so a retried request is only counted once:
+//
+//     breaker := &interstellar.CircuitBreakerRequester{Requester: client.Requester}
+//     client.Requester = breaker
+//
+// The zero values of Window, FailureRatio, and Cooldown use the defaults. A CircuitBreakerRequester must not be copied after first use.
+type CircuitBreakerRequester struct {
+	Requester
+	// Window is the number of most recent responses used to compute the failure ratio; the circuit cannot open until the window is full
+	Window int
+	// FailureRatio is the ratio (0-1] of throttled or unavailable responses in the window which opens the circuit
+	FailureRatio float64
+	// Cooldown is how long the circuit stays open before a probe request is sent
+	Cooldown time.Duration
+	// OnStateChange is optional, and is called (while the breaker is locked) whenever the state changes, such as to record metrics
+	OnStateChange func(from, to CircuitState)
+
+	mu       sync.Mutex
+	state    CircuitState
+	outcomes []bool
+	next     int
+	count    int
+	failures int
+	openedAt time.Time
+	probing  bool
+	// generation is incremented by every state change, so responses to requests sent before it are not recorded
+	generation uint64
+}
+
+// State gets the current state of the circuit
+// An open circuit whose cooldown has passed is reported as half-open.
+func (r *CircuitBreakerRequester) State() CircuitState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state == CircuitOpen && time.Since(r.openedAt) >= r.cooldown() {
+		return CircuitHalfOpen
+	}
+	return r.state
+}
+
+// Do sends the request, unless the circuit is open
+func (r *CircuitBreakerRequester) Do(req *http.Request) (*http.Response, error) {
+	generation, probe, err := r.allow()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.Requester.Do(req)
+	r.record(generation, probe, err == nil && !circuitFailure(resp.StatusCode), err != nil)
+	return resp, err
+}
+
+func circuitFailure(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// allow checks if a request may be sent, and moves an open circuit to half-open after the cooldown
+// It returns the generation the request is sent in, and whether it is the probe of a half-open circuit.
+func (r *CircuitBreakerRequester) allow() (uint64, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch r.state {
+	case CircuitOpen:
+		if time.Since(r.openedAt) < r.cooldown() {
+			return 0, false, ErrCircuitOpen
+		}
+		r.setState(CircuitHalfOpen)
+		r.probing = true
+		return r.generation, true, nil
+	case CircuitHalfOpen:
+		if r.probing {
+			return 0, false, ErrCircuitOpen
+		}
+		r.probing = true
+		return r.generation, true, nil
+	default:
+		return r.generation, false, nil
+	}
+}
+
+// record the outcome of a request sent in the generation
+// Responses to requests sent before the last state change are ignored; in particular, only the probe moves a half-open circuit,
+// not a slow response to a request sent before the circuit opened.
+// Transport errors are not counted while closed, since they are not a sign of throttling, but a failed probe opens the circuit again.
+func (r *CircuitBreakerRequester) record(generation uint64, probe bool, ok bool, transportErr bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if generation != r.generation {
+		return
+	}
+	if r.state == CircuitHalfOpen {
+		if !probe {
+			return
+		}
+		r.probing = false
+		if ok {
+			r.reset()
+			r.setState(CircuitClosed)
+		} else {
+			r.open()
+		}
+		return
+	}
+	if r.state != CircuitClosed || transportErr {
+		return
+	}
+	window := r.window()
+	if len(r.outcomes) != window {
+		r.reset()
+	}
+	if r.outcomes[r.next] {
+		r.failures--
+	}
+	r.outcomes[r.next] = !ok
+	if !ok {
+		r.failures++
+	}
+	r.next = (r.next + 1) % window
+	if r.count < window {
+		r.count++
+	}
+	if r.count == window && float64(r.failures)/float64(window) >= r.failureRatio() {
+		r.open()
+	}
+}
+
+func (r *CircuitBreakerRequester) open() {
+	r.openedAt = time.Now()
+	r.setState(CircuitOpen)
+}
+
+// reset clears the window of outcomes
+func (r *CircuitBreakerRequester) reset() {
+	r.outcomes = make([]bool, r.window())
+	r.next = 0
+	r.count = 0
+	r.failures = 0
+}
+
+func (r *CircuitBreakerRequester) setState(state CircuitState) {
+	if r.state == state {
+		return
+	}
+	from := r.state
+	r.state = state
+	r.generation++
+	if r.OnStateChange != nil {
+		r.OnStateChange(from, state)
+	}
+}
+
+func (r *CircuitBreakerRequester) window() int {
+	if r.Window > 0 {
+		return r.Window
+	}
+	return DefaultCircuitBreakerWindow
+}
+
+func (r *CircuitBreakerRequester) failureRatio() float64 {
+	if r.FailureRatio > 0 {
+		return r.FailureRatio
+	}
+	return DefaultCircuitBreakerFailureRatio
+}
+
+func (r *CircuitBreakerRequester) cooldown() time.Duration {
+	if r.Cooldown > 0 {
+		return r.Cooldown
+	}
+	return DefaultCircuitBreakerCooldown
+}
@@ -0,0 +1,178 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package interstellar_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jet/go-interstellar"
+	"github.com/jet/go-interstellar/internal/testutil"
+)
+
+func TestCircuitBreakerRequester(t *testing.T) {
+	status := http.StatusOK
+	sent := 0
+	var transitions []string
+	breaker := &interstellar.CircuitBreakerRequester{
+		Requester: testutil.RequesterFunc(func(req *http.Request) (*http.Response, error) {
+			sent++
+			return testutil.NewResponse(req, status, nil, `{}`), nil
+		}),
+		Window:       4,
+		FailureRatio: 0.5,
+		Cooldown:     20 * time.Millisecond,
+		OnStateChange: func(from, to interstellar.CircuitState) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	}
+	do := func() error {
+		req, _ := http.NewRequest(http.MethodGet, "https://localhost:8081/dbs", nil)
+		_, err := breaker.Do(req)
+		return err
+	}
+
+	// a full window with 1/4 throttled stays closed
+	for i, s := range []int{http.StatusOK, http.StatusTooManyRequests, http.StatusOK, http.StatusOK} {
+		status = s
+		if err := do(); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	if breaker.State() != interstellar.CircuitClosed {
+		t.Fatalf("expected closed, got %s", breaker.State())
+	}
+
+	// the second throttled response in the window opens the circuit
+	status = http.StatusServiceUnavailable
+	if err := do(); err != nil {
+		t.Fatal(err)
+	}
+	if breaker.State() != interstellar.CircuitOpen {
+		t.Fatalf("expected open, got %s", breaker.State())
+	}
+	before := sent
+	if err := do(); err != interstellar.ErrCircuitOpen {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if sent != before {
+		t.Fatal("expected the request not to be sent while open")
+	}
+
+	// a throttled probe opens it again
+	time.Sleep(30 * time.Millisecond)
+	if breaker.State() != interstellar.CircuitHalfOpen {
+		t.Fatalf("expected half-open, got %s", breaker.State())
+	}
+	status = http.StatusTooManyRequests
+	if err := do(); err != nil {
+		t.Fatal(err)
+	}
+	if breaker.State() != interstellar.CircuitOpen {
+		t.Fatalf("expected open after a failed probe, got %s", breaker.State())
+	}
+
+	// a successful probe closes it
+	time.Sleep(30 * time.Millisecond)
+	status = http.StatusOK
+	if err := do(); err != nil {
+		t.Fatal(err)
+	}
+	if breaker.State() != interstellar.CircuitClosed {
+		t.Fatalf("expected closed after a successful probe, got %s", breaker.State())
+	}
+	expected := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if len(transitions) != len(expected) {
+		t.Fatalf("expected transitions %v, got %v", expected, transitions)
+	}
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Errorf("expected transitions %v, got %v", expected, transitions)
+			break
+		}
+	}
+}
+
+func TestCircuitBreakerStaleResponse(t *testing.T) {
+	var mu sync.Mutex
+	statuses := map[string]int{}
+	arrived := make(chan string, 4)
+	release := map[string]chan struct{}{
+		"/stale": make(chan struct{}),
+		"/probe": make(chan struct{}),
+	}
+	breaker := &interstellar.CircuitBreakerRequester{
+		Requester: testutil.RequesterFunc(func(req *http.Request) (*http.Response, error) {
+			arrived <- req.URL.Path
+			if ch, ok := release[req.URL.Path]; ok {
+				<-ch
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			return testutil.NewResponse(req, statuses[req.URL.Path], nil, `{}`), nil
+		}),
+		Window:       2,
+		FailureRatio: 1,
+		Cooldown:     10 * time.Millisecond,
+	}
+	do := func(path string, status int) <-chan error {
+		mu.Lock()
+		statuses[path] = status
+		mu.Unlock()
+		errc := make(chan error, 1)
+		go func() {
+			req, _ := http.NewRequest(http.MethodGet, "https://localhost:8081"+path, nil)
+			_, err := breaker.Do(req)
+			errc <- err
+		}()
+		return errc
+	}
+
+	// a request is sent while closed, but its response is slow
+	stale := do("/stale", http.StatusOK)
+	<-arrived
+	for _, path := range []string{"/a", "/b"} {
+		if err := <-do(path, http.StatusTooManyRequests); err != nil {
+			t.Fatal(err)
+		}
+		<-arrived
+	}
+	if breaker.State() != interstellar.CircuitOpen {
+		t.Fatalf("expected open, got %s", breaker.State())
+	}
+	time.Sleep(20 * time.Millisecond)
+	probe := do("/probe", http.StatusTooManyRequests)
+	<-arrived
+
+	// the stale response is not the probe, so it does not close the circuit
+	close(release["/stale"])
+	if err := <-stale; err != nil {
+		t.Fatal(err)
+	}
+	if breaker.State() != interstellar.CircuitHalfOpen {
+		t.Fatalf("expected the stale response to be ignored, got %s", breaker.State())
+	}
+	close(release["/probe"])
+	if err := <-probe; err != nil {
+		t.Fatal(err)
+	}
+	if breaker.State() != interstellar.CircuitOpen {
+		t.Fatalf("expected the failed probe to open the circuit again, got %s", breaker.State())
+	}
+}
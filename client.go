@@ -18,6 +18,7 @@ package interstellar
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/jet/go-mantis/rest"
 )
@@ -31,6 +32,37 @@ type Client struct {
 	Endpoint  string
 	Authorizer
 	Requester
+
+	// Logger is optional, and receives a summary of each request made by the client's operations
+	Logger Logger
+
+	// GenerateActivityID assigns a random UUID to the x-ms-activity-id header of each request which does not already have one
+	// The activity id is echoed into the ResponseMetadata, so requests can be correlated even when the server does not return one.
+	GenerateActivityID bool
+
+	// MaxResponseBytes limits the size of each response body which is read, if greater than zero
+	// Reading a larger response fails with ErrResponseTooLarge. This protects against huge pages (such as from queries on user-controlled collections) exhausting memory.
+	MaxResponseBytes int64
+
+	// PageThrottleRetries is the number of times ListResources retries a page which was throttled (http status code 429), preserving the continuation
+	// Each retry waits for the x-ms-retry-after-ms of the response, or an exponential backoff if it is not given.
+	// If zero, DefaultPageThrottleRetries is used; if negative, throttled pages are not retried.
+	PageThrottleRetries int
+
+	// DefaultOptions are applied to every request made by the client, before the options of the request itself (which take precedence)
+	// See WithOptions
+	DefaultOptions RequestOptions
+
+	// ValidateResourceLinks checks that the ResourceLink of each request is consistent with its Path, for the known resource types
+	// A mismatched ResourceLink is signed into the Authorization header and rejected by the server with 401 Unauthorized,
+	// so this is a debugging aid for custom requests; NewHTTPRequest returns an error instead of sending the request.
+	ValidateResourceLinks bool
+
+	// httpClient is the http client created by NewClient when no Requester was supplied; it is cleaned up by Close
+	httpClient *http.Client
+
+	// limiter bounds the number of requests in flight; it is set by WithMaxConcurrentRequests
+	limiter *requestLimiter
 }
 
 // Requester is an interface for sending HTTP requests and receiving responses
@@ -47,20 +79,121 @@ type Authorizer interface {
 
 // NewClient creates client to the given CoasmosDB account in the ConnectionString
 // And will use the Requester to send HTTP requests and read responses
-//
+// The Requester is wrapped to retry throttled requests and requests which failed with transient network errors (see TransientRetryRequester)
+// If the Requester is nil, an HTTP client is created, and is owned by the Client; see Close.
 func NewClient(cs ConnectionString, req Requester) (*Client, error) {
+	return NewClientWithOptions(cs, req, ClientOptions{})
+}
+
+// ClientOptions configures the retries of a Client created by NewClientWithOptions
+// The zero value is the configuration of NewClient: throttled requests are retried after their Retry-After delay, and requests
+// which fail with a transient network error are retried DefaultTransientRetries times, starting after DefaultTransientBackoff.
+type ClientOptions struct {
+	// RetryStatusCodes are the status codes of responses which are retried after the delay in the RetryHeaderName header
+	// If empty, only 429 Too Many Requests is retried.
+	// For example, to also honor the Retry-After header some gateways return with 503 Service Unavailable:
+	//
+	//     interstellar.ClientOptions{
+	//         RetryStatusCodes: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable},
+	//     }
+	//
+	RetryStatusCodes []int
+	// RetryHeaderName is the name of the response header with the delay before retrying; if empty, "Retry-After" is used
+	RetryHeaderName string
+	// TransientRetries is the number of times a request which failed with a transient network error is retried (see TransientRetryRequester)
+	// If zero, DefaultTransientRetries is used; if negative, the Requester is not wrapped and such requests are not retried.
+	TransientRetries int
+	// TransientBackoff is the delay before the first retry of a transient network error, doubled on each subsequent retry
+	// If zero, DefaultTransientBackoff is used; if negative, the retries are not delayed.
+	TransientBackoff time.Duration
+}
+
+// NewClientWithOptions is the same as NewClient, but the retries of the Requester are configured by the options
+func NewClientWithOptions(cs ConnectionString, req Requester, opts ClientOptions) (*Client, error) {
+	var owned *http.Client
 	if req == nil {
-		req = rest.HTTPClient()
+		owned = rest.HTTPClient()
+		req = owned
+	}
+	if opts.TransientRetries >= 0 {
+		retries, backoff := opts.TransientRetries, opts.TransientBackoff
+		if retries == 0 {
+			retries = DefaultTransientRetries
+		}
+		if backoff == 0 {
+			backoff = DefaultTransientBackoff
+		}
+		req = &TransientRetryRequester{
+			Requester:  req,
+			MaxRetries: retries,
+			Backoff:    backoff,
+		}
 	}
 	return &Client{
+		httpClient: owned,
 		UserAgent:  DefaultUserAgent,
 		Endpoint:   cs.Endpoint,
 		Authorizer: cs.AccountKey,
 		Requester: &rest.RetryAfterRequester{
-			// Defaults ...
-			//StatusCodes: []int{http.StatusTooManyRequests},
-			//HeaderName: "Retry-After",
-			Requester: req,
+			// Defaults to 429 and "Retry-After" when not set
+			StatusCodes: opts.RetryStatusCodes,
+			HeaderName:  opts.RetryHeaderName,
+			Requester:   req,
 		},
 	}, nil
 }
+
+// WithUserAgentSuffix creates a copy of the client which appends the suffix to its user agent, separated by a space
+// If the client has no UserAgent, the suffix is appended to DefaultUserAgent.
+// For example, a library built on interstellar can identify itself while preserving the base user agent:
+//
+//     client = client.WithUserAgentSuffix("MyLibrary/2.0") // Go-Interstellar/0.1 MyLibrary/2.0
+//
+func (c *Client) WithUserAgentSuffix(suffix string) *Client {
+	cc := *c
+	cc.UserAgent = c.userAgent()
+	if suffix != "" {
+		cc.UserAgent += " " + suffix
+	}
+	return &cc
+}
+
+// WithEndpoint creates a copy of the client which sends requests to a different endpoint, such as a regional endpoint of the account
+// Requests are still authorized with the same Authorizer, and sent with the same Requester.
+func (c *Client) WithEndpoint(endpoint string) *Client {
+	cc := *c
+	cc.Endpoint = endpoint
+	return &cc
+}
+
+// Close closes the idle connections of the HTTP client created by NewClient, when no Requester was supplied to it
+// Callers who supply their own Requester are responsible for cleaning it up; Close does nothing to it.
+// Copies of the client (such as from WithUserAgentSuffix) share the same HTTP client, so should not be used after Close.
+func (c *Client) Close() error {
+	if c.httpClient != nil {
+		c.httpClient.CloseIdleConnections()
+	}
+	return nil
+}
+
+// WithOptions creates a copy of the client which applies the options to every request, after any DefaultOptions the client already has
+// For example, to use session consistency with the same session token for a series of operations:
+//
+//     session := client.WithOptions(&interstellar.CommonRequestOptions{
+//         ConsistencyLevel: interstellar.ConsistencySession,
+//         SessionToken:     token,
+//     })
+//
+// The options given to each operation are applied afterwards, so they override the defaults.
+func (c *Client) WithOptions(opts RequestOptions) *Client {
+	cc := *c
+	cc.DefaultOptions = mergeOptions(c.DefaultOptions, opts)
+	return &cc
+}
+
+func (c *Client) userAgent() string {
+	if c.UserAgent != "" {
+		return c.UserAgent
+	}
+	return DefaultUserAgent
+}
@@ -20,11 +20,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"strings"
 
-	"github.com/jet/go-mantis/rest"
 	"github.com/pkg/errors"
 )
 
@@ -37,8 +37,77 @@ const (
 
 	// ErrResourceNotModified is returned from an http status code 304
 	ErrResourceNotModified = Error("interstellar: resource not modified")
+
+	// ErrResourceConflict is returned when creating a resource which already exists (http status code 409)
+	ErrResourceConflict = Error("interstellar: resource conflict")
+
+	// ErrResponseTooLarge is returned when reading a response body which is larger than the Client's MaxResponseBytes
+	ErrResponseTooLarge = Error("interstellar: response body exceeds the maximum size")
+
+	// ErrPayloadTooLarge is returned when a write is rejected because the document (or batch) is too large (http status code 413),
+	// or when a document is larger than the MaxDocumentBytes of its request, and is not sent.
+	ErrPayloadTooLarge = Error("interstellar: request payload too large")
+
+	// ErrDocumentTooLarge is the same error as ErrPayloadTooLarge
+	//
+	// Deprecated: use ErrPayloadTooLarge, which is also returned when the server rejects the document.
+	ErrDocumentTooLarge = ErrPayloadTooLarge
 )
 
+// do sends the request with the Requester, once it is within the Client's MaxConcurrentRequests
+// A summary of the exchange is logged if the Client has a Logger, and the response body is limited to the Client's MaxResponseBytes
+// The caller must close the response body (when there is no error) to release the request.
+func (c *Client) do(req *http.Request, request ClientRequest) (*http.Response, error) {
+	release, err := c.acquireRequest(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.doLogged(req, request)
+	if tap := requestTapFrom(req.Context()); tap != nil {
+		tap(req, resp)
+	}
+	if acc := ruAccumulatorFrom(req.Context()); acc != nil {
+		acc.add(resp)
+	}
+	if err == nil && resp.Body != nil {
+		resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	} else {
+		release()
+	}
+	if err == nil && c.MaxResponseBytes > 0 && resp.Body != nil {
+		resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: c.MaxResponseBytes}
+	}
+	return resp, err
+}
+
+// limitedBody returns ErrResponseTooLarge once more than the remaining number of bytes have been read
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// check if the body ends exactly at the limit; a reader may return no bytes without reaching the end
+		var one [1]byte
+		for {
+			n, err := b.ReadCloser.Read(one[:])
+			if n > 0 {
+				return 0, ErrResponseTooLarge
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
 // PaginateRawResources is run by the List* operations with each page of results from the API.
 // Returning `false` from the function will stop pagination and return a nil error.
 // Returning a non-nil `error` from this function will stop pagination and return the error
@@ -48,6 +117,7 @@ type PaginateRawResources func(resList []json.RawMessage, meta ResponseMetadata)
 //
 // If the ClientRequest.Method is not set, it will default to POST.
 // If it is given, it must be PUT or POST; otherwise an error will be returned.
+// If a resource with the same ID already exists, ErrResourceConflict is returned.
 //
 // For example, this can be used to create a new collection inside a database, a new document inside a collection, or update a document with new data.
 func (c *Client) CreateOrReplaceResource(ctx context.Context, request ClientRequest) ([]byte, *ResponseMetadata, error) {
@@ -65,22 +135,31 @@ func (c *Client) CreateOrReplaceResource(ctx context.Context, request ClientRequ
 	if err != nil {
 		return nil, nil, err
 	}
-	resp, err := c.Requester.Do(req)
+	resp, err := c.do(req, request)
 	if err != nil {
 		return nil, nil, err
 	}
 	meta := GetResponseMetadata(resp)
 	switch resp.StatusCode {
-	case http.StatusOK:
-		fallthrough
-	case http.StatusCreated:
+	case http.StatusOK, http.StatusCreated:
 		defer resp.Body.Close()
 		body, err := ioutil.ReadAll(resp.Body)
 		return body, &meta, err
+	case http.StatusNoContent:
+		// such as a write with Prefer: return=minimal
+		resp.Body.Close()
+		return nil, &meta, nil
 	case http.StatusPreconditionFailed:
+		resp.Body.Close()
 		return nil, &meta, ErrPreconditionFailed
+	case http.StatusConflict:
+		resp.Body.Close()
+		return nil, &meta, ErrResourceConflict
+	case http.StatusRequestEntityTooLarge:
+		resp.Body.Close()
+		return nil, &meta, ErrPayloadTooLarge
 	default:
-		return nil, &meta, rest.NewErrorHTTPResponse(resp)
+		return nil, &meta, newCosmosError(resp)
 	}
 }
 
@@ -92,7 +171,7 @@ func (c *Client) GetResource(ctx context.Context, request ClientRequest) ([]byte
 	if err != nil {
 		return nil, nil, err
 	}
-	resp, err := c.Requester.Do(req)
+	resp, err := c.do(req, request)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -105,13 +184,17 @@ func (c *Client) GetResource(ctx context.Context, request ClientRequest) ([]byte
 			return nil, &meta, err
 		}
 		return body, &meta, nil
+	case http.StatusNotModified:
+		resp.Body.Close()
+		return nil, &meta, ErrResourceNotModified
 	case http.StatusPreconditionFailed:
+		resp.Body.Close()
 		return nil, &meta, ErrPreconditionFailed
 	case http.StatusNotFound:
 		resp.Body.Close()
 		return nil, &meta, ErrResourceNotFound
 	default:
-		return nil, &meta, rest.NewErrorHTTPResponse(resp)
+		return nil, &meta, newCosmosError(resp)
 	}
 }
 
@@ -128,6 +211,11 @@ func requestIsQuery(req *http.Request) {
 // If PaginateRawResources function returns (false, nil), then pagination will stop, and ListResults will return without error.
 // If PaginateRawResources function returns a non-nil error, then pagination will stop, and ListResults will return that error.
 // Pagination will also stop after the last page is returned from the API
+// Empty pages are given to the PaginateRawResources function as a nil list; they may still have a continuation (such as in cross-partition queries).
+// A response which does not contain the key is malformed, and an error wrapping ErrKeyNotFound is returned; compare errors.Cause(err) with ErrKeyNotFound.
+// A page larger than the Client's MaxResponseBytes fails with ErrResponseTooLarge (not wrapped).
+// If the context is cancelled or expires, pagination will stop before the next page is requested, and the context's error is returned.
+// A page which is throttled (http status code 429) is retried from the same continuation, up to PageThrottleRetries times; see Client.PageThrottleRetries.
 func (c *Client) ListResources(ctx context.Context, key string, request ClientRequest, fn PaginateRawResources) error {
 	prequest := &request
 	prequest.Method = strings.ToUpper(request.Method)
@@ -156,26 +244,71 @@ func (c *Client) ListResources(ctx context.Context, key string, request ClientRe
 	default:
 		return errors.Errorf("interstellar: Invalid request method '%s'; must be either GET or POST", request.Method)
 	}
-	req, err := c.NewHTTPRequest(ctx, request)
-	if err != nil {
-		return err
+	// newRequest builds the request for a page; the body of a query is reset, since it is consumed by each request
+	newRequest := func(continuation, sessionToken string) (*http.Request, error) {
+		if body != nil {
+			request.Body = bytes.NewBuffer(body)
+		}
+		req, err := c.NewHTTPRequest(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		if continuation != "" {
+			req.Header.Set(HeaderSessionToken, sessionToken)
+			req.Header.Set(HeaderContinuation, continuation)
+		}
+		return req, nil
 	}
+	var continuation, sessionToken string
+	throttled := 0
 	for {
-		resp, err := c.Requester.Do(req)
+		if ctx != nil {
+			// honor cancellation between pages, since the pagination function may take a while
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+		}
+		req, err := newRequest(continuation, sessionToken)
 		if err != nil {
 			return err
 		}
+		resp, err := c.do(req, request)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && throttled < c.pageThrottleRetries() {
+			// retry the throttled page from the same continuation, so earlier pages are not lost
+			wait := c.throttleBackoff(throttled, GetResponseMetadata(resp).RetryAfterMS)
+			resp.Body.Close()
+			throttled++
+			if err = sleepContext(ctx, wait); err != nil {
+				return err
+			}
+			continue
+		}
+		throttled = 0
 		if resp.StatusCode != http.StatusOK {
 			if resp.StatusCode == http.StatusNotModified {
+				resp.Body.Close()
 				return ErrResourceNotModified
 			}
-			return rest.NewErrorHTTPResponse(resp)
+			return newCosmosError(resp)
 		}
 		meta := GetResponseMetadata(resp)
 		results, err := ParseArrayFromResponse(resp.Body, key)
 		resp.Body.Close()
 		if err != nil {
-			return err
+			if errors.Cause(err) == ErrResponseTooLarge {
+				return ErrResponseTooLarge
+			}
+			// an empty page is not an error; only a response without the results key (or which is not JSON) is malformed
+			return errors.Wrapf(err, "interstellar: malformed response listing '%s'", key)
+		}
+		if len(results) == 0 {
+			// an empty page is given to the pagination function as a nil list
+			results = nil
 		}
 		ok, err := fn(results, meta)
 		if err != nil {
@@ -184,22 +317,23 @@ func (c *Client) ListResources(ctx context.Context, key string, request ClientRe
 		if !ok {
 			return nil
 		}
-		if cont := resp.Header.Get(HeaderContinuation); cont != "" {
-			if body != nil {
-				// reset query string body
-				request.Body = bytes.NewBuffer(body)
-			}
-			req, err = c.NewHTTPRequest(ctx, request)
-			if err != nil {
-				return err
-			}
-			req.Header.Set(HeaderSessionToken, meta.SessionToken)
-			req.Header.Set(HeaderContinuation, meta.Continuation)
-			continue
+		if meta.Continuation == "" {
+			return nil
 		}
-		break
+		continuation, sessionToken = meta.Continuation, meta.SessionToken
 	}
-	return nil
+}
+
+// ListGeneric lists the resources of an arbitrary feed, such as a resource type which does not have a dedicated client yet
+// The path is the URL path of the feed (such as "/dbs/db1/users"), and the key is the property of the response which lists the resources (such as "Users").
+// See ListResources for how pagination works.
+func (c *Client) ListGeneric(ctx context.Context, path, resourceLink string, rt ResourceType, key string, opts RequestOptions, fn PaginateRawResources) error {
+	return c.ListResources(ctx, key, ClientRequest{
+		Path:         path,
+		ResourceLink: resourceLink,
+		ResourceType: rt,
+		Options:      opts,
+	}, fn)
 }
 
 // DeleteResource issues a delete command against a resource designate by the request
@@ -209,7 +343,7 @@ func (c *Client) DeleteResource(ctx context.Context, request ClientRequest) (boo
 	if err != nil {
 		return false, nil, err
 	}
-	resp, err := c.Requester.Do(req)
+	resp, err := c.do(req, request)
 	if err != nil {
 		return false, nil, err
 	}
@@ -219,11 +353,12 @@ func (c *Client) DeleteResource(ctx context.Context, request ClientRequest) (boo
 		resp.Body.Close()
 		return true, &meta, nil
 	case http.StatusPreconditionFailed:
+		resp.Body.Close()
 		return false, &meta, ErrPreconditionFailed
 	case http.StatusNotFound:
 		resp.Body.Close()
 		return false, &meta, ErrResourceNotFound
 	default:
-		return false, &meta, rest.NewErrorHTTPResponse(resp)
+		return false, &meta, newCosmosError(resp)
 	}
 }
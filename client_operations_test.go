@@ -0,0 +1,290 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package interstellar_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jet/go-interstellar"
+	"github.com/jet/go-interstellar/internal/testutil"
+	"github.com/jet/go-mantis/rest"
+	"github.com/pkg/errors"
+)
+
+func TestListResourcesCancelBetweenPages(t *testing.T) {
+	fake := testutil.NewFakeRequester().On(http.MethodGet, "/dbs/db1/colls/col1/docs", testutil.Page(`{"Documents":[{"id":"1"}]}`, "next"))
+	client := testutil.NewStubClient(fake)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pages := 0
+	err := client.WithDatabase("db1").WithCollection("col1").ListDocumentsRaw(ctx, nil, func(resList []json.RawMessage, meta interstellar.ResponseMetadata) (bool, error) {
+		pages++
+		cancel()
+		return true, nil
+	})
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if requests := len(fake.Requests()); pages != 1 || requests != 1 {
+		t.Errorf("expected pagination to stop after 1 page, got %d pages and %d requests", pages, requests)
+	}
+}
+
+func TestCreateResourceConflict(t *testing.T) {
+	conflict := testutil.Status(http.StatusConflict, `{"code":"Conflict","message":"Resource with specified id or name already exists."}`)
+	fake := testutil.NewFakeRequester()
+	for _, path := range []string{"/dbs", "/dbs/db1/colls", "/dbs/db1/colls/col1/docs", "/dbs/db1/colls/col1/sprocs", "/dbs/db1/colls/col1/udfs"} {
+		fake.On(http.MethodPost, path, conflict)
+	}
+	client := testutil.NewStubClient(fake)
+	ctx := context.Background()
+	db := client.WithDatabase("db1")
+	coll := db.WithCollection("col1")
+	writes := map[string]func() error{
+		"CreateDatabase": func() error {
+			_, _, err := client.CreateDatabase(ctx, "db1", nil)
+			return err
+		},
+		"CreateCollection": func() error {
+			_, _, err := db.CreateCollection(ctx, interstellar.CreateCollectionRequest{ID: "col1"})
+			return err
+		},
+		"CreateDocument": func() error {
+			_, _, err := coll.CreateDocument(ctx, interstellar.CreateDocumentRequest{Body: []byte(`{"id":"1"}`)})
+			return err
+		},
+		"CreateStoredProcedure": func() error {
+			_, _, err := coll.CreateStoredProcedure(ctx, interstellar.CreateStoredProcedureRequest{ID: "sp1", Body: "function() {}"})
+			return err
+		},
+		"CreateUserDefinedFunction": func() error {
+			_, _, err := coll.CreateUserDefinedFunction(ctx, interstellar.CreateUserDefinedFunctionRequest{ID: "udf1", Body: "function() {}"})
+			return err
+		},
+	}
+	for name, write := range writes {
+		t.Run(name, func(t *testing.T) {
+			if err := write(); err != interstellar.ErrResourceConflict {
+				t.Errorf("expected ErrResourceConflict, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGetResourceNotModified(t *testing.T) {
+	fake := testutil.NewFakeRequester().On(http.MethodGet, "/dbs/db1/colls/col1", testutil.NotModified())
+	client := testutil.NewStubClient(fake)
+	body, meta, err := client.GetResource(context.Background(), interstellar.ClientRequest{
+		Path:         "/dbs/db1/colls/col1",
+		ResourceType: interstellar.ResourceCollections,
+		ResourceLink: "dbs/db1/colls/col1",
+		Options:      &interstellar.CommonRequestOptions{IfNoneMatch: `"etag"`},
+	})
+	if err != interstellar.ErrResourceNotModified {
+		t.Fatalf("expected ErrResourceNotModified, got %v", err)
+	}
+	if body != nil || meta == nil {
+		t.Errorf("expected no body and the response metadata, got body=%s meta=%v", body, meta)
+	}
+	if reqs := fake.Requests(); len(reqs) != 1 || reqs[0].Header.Get(interstellar.HeaderIfNoneMatch) != `"etag"` {
+		t.Errorf("expected If-None-Match header to be set")
+	}
+}
+
+func TestClientMaxResponseBytes(t *testing.T) {
+	const body = `{"Documents":[{"id":"1"},{"id":"2"}]}`
+	client := testutil.NewStubClient(testutil.NewFakeRequester().On(http.MethodGet, "/dbs/db1/colls/col1/docs", testutil.Page(body, "")))
+	list := func() error {
+		return client.WithDatabase("db1").WithCollection("col1").ListDocumentsRaw(context.Background(), nil, func(resList []json.RawMessage, meta interstellar.ResponseMetadata) (bool, error) {
+			return true, nil
+		})
+	}
+	client.MaxResponseBytes = int64(len(body))
+	if err := list(); err != nil {
+		t.Fatalf("expected a response of exactly MaxResponseBytes to be read, got %v", err)
+	}
+	client.MaxResponseBytes = int64(len(body)) - 1
+	if err := list(); err != interstellar.ErrResponseTooLarge {
+		t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+	}
+}
+
+// stutteringReader returns no bytes (and no error) before each read of the underlying reader
+type stutteringReader struct {
+	io.Reader
+	stutter bool
+}
+
+func (r *stutteringReader) Read(p []byte) (int, error) {
+	if r.stutter = !r.stutter; r.stutter {
+		return 0, nil
+	}
+	return r.Reader.Read(p)
+}
+
+func TestClientMaxResponseBytesEmptyReads(t *testing.T) {
+	const body = `{"Documents":[{"id":"1"},{"id":"2"}]}`
+	client := testutil.NewStubClient(testutil.RequesterFunc(func(req *http.Request) (*http.Response, error) {
+		resp := testutil.NewResponse(req, http.StatusOK, nil, "")
+		resp.Body = ioutil.NopCloser(&stutteringReader{Reader: strings.NewReader(body)})
+		return resp, nil
+	}))
+	client.MaxResponseBytes = int64(len(body)) - 1
+	err := client.WithDatabase("db1").WithCollection("col1").ListDocumentsRaw(context.Background(), nil, func(resList []json.RawMessage, meta interstellar.ResponseMetadata) (bool, error) {
+		return true, nil
+	})
+	if err != interstellar.ErrResponseTooLarge {
+		t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+	}
+}
+
+func TestListResourcesEmptyPages(t *testing.T) {
+	examples := []struct {
+		name  string
+		body  string
+		pages int
+		err   error
+	}{
+		{name: "empty", body: `{"Documents":[],"_count":0}`, pages: 1},
+		{name: "null", body: `{"Documents":null}`, pages: 1},
+		{name: "missing-key", body: `{"_count":0}`, err: interstellar.ErrKeyNotFound},
+	}
+	for _, ex := range examples {
+		t.Run(ex.name, func(t *testing.T) {
+			client := testutil.NewStubClient(testutil.NewFakeRequester().On(http.MethodGet, "/dbs/db1/colls/col1/docs", testutil.Page(ex.body, "")))
+			pages := 0
+			err := client.WithDatabase("db1").WithCollection("col1").ListDocumentsRaw(context.Background(), nil, func(resList []json.RawMessage, meta interstellar.ResponseMetadata) (bool, error) {
+				pages++
+				if resList != nil {
+					t.Errorf("expected a nil page, got %v", resList)
+				}
+				return true, nil
+			})
+			if errors.Cause(err) != ex.err {
+				t.Fatalf("expected error %v, got %v", ex.err, err)
+			}
+			if pages != ex.pages {
+				t.Errorf("expected %d pages, got %d", ex.pages, pages)
+			}
+		})
+	}
+}
+
+func TestListGeneric(t *testing.T) {
+	client := testutil.NewStubClient(testutil.NewFakeRequester().
+		On(http.MethodGet, "/dbs/db1/users", testutil.Page(`{"Users":[{"id":"u1"},{"id":"u2"}],"_count":2}`, "")))
+	var users []json.RawMessage
+	err := client.ListGeneric(context.Background(), "/dbs/db1/users", "dbs/db1", interstellar.ResourceType("users"), "Users", nil, func(resList []json.RawMessage, meta interstellar.ResponseMetadata) (bool, error) {
+		users = append(users, resList...)
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 {
+		t.Errorf("expected 2 users, got %d", len(users))
+	}
+}
+
+func TestListResourcesThrottledPage(t *testing.T) {
+	fake := testutil.NewFakeRequester().On(http.MethodPost, "/dbs/db1/colls/col1/docs",
+		testutil.Page(`{"Documents":[{"id":"1"}]}`, "page2"),
+		testutil.Throttled(1),
+		testutil.Page(`{"Documents":[{"id":"2"}]}`, ""))
+	client := testutil.NewStubClient(fake)
+	var ids []string
+	err := client.WithDatabase("db1").WithCollection("col1").QueryDocumentsRaw(context.Background(), &interstellar.Query{Query: "SELECT * FROM c"}, func(resList []json.RawMessage, meta interstellar.ResponseMetadata) (bool, error) {
+		for _, raw := range resList {
+			ids = append(ids, string(raw))
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 {
+		t.Errorf("expected both pages, got %v", ids)
+	}
+	var bodies, continuations []string
+	for _, req := range fake.Requests() {
+		bodies = append(bodies, req.Body)
+		continuations = append(continuations, req.Header.Get(interstellar.HeaderContinuation))
+	}
+	if len(continuations) != 3 || continuations[1] != "page2" || continuations[2] != "page2" {
+		t.Errorf("expected the throttled page to be retried from its continuation, got %v", continuations)
+	}
+	for i, body := range bodies {
+		if body != bodies[0] || body == "" {
+			t.Errorf("request %d: expected the query body to be re-sent, got %q", i, body)
+		}
+	}
+
+	client.PageThrottleRetries = -1
+	fake = testutil.NewFakeRequester().On(http.MethodGet, "/dbs/db1/colls/col1/docs", testutil.Throttled(0))
+	client.Requester = fake
+	err = client.WithDatabase("db1").WithCollection("col1").ListDocumentsRaw(context.Background(), nil, func(resList []json.RawMessage, meta interstellar.ResponseMetadata) (bool, error) {
+		return true, nil
+	})
+	if requests := len(fake.Requests()); err == nil || requests != 1 {
+		t.Errorf("expected the throttled page not to be retried, got %d requests (%v)", requests, err)
+	}
+}
+
+func TestCosmosError(t *testing.T) {
+	hdr := make(http.Header)
+	hdr.Set(interstellar.HeaderSubStatus, "1001")
+	hdr.Set(interstellar.HeaderActivityID, "a1b2c3")
+	client := testutil.NewStubClient(testutil.NewFakeRequester().On(http.MethodPost, "/dbs/db1/colls/col1/docs", testutil.FakeResponse{
+		StatusCode: http.StatusBadRequest,
+		Header:     hdr,
+		Body:       `{"code":"BadRequest","message":"Partition key provided either doesn't correspond to definition in the collection or doesn't match partition key field values specified in the document."}`,
+	}))
+	_, _, err := client.WithDatabase("db1").WithCollection("col1").CreateDocument(context.Background(), interstellar.CreateDocumentRequest{
+		PartitionKey: []string{"wrong"},
+		Body:         []byte(`{"id":"doc1"}`),
+	})
+	cerr, ok := err.(*interstellar.CosmosError)
+	if !ok {
+		t.Fatalf("expected a *CosmosError, got %T: %v", err, err)
+	}
+	if cerr.StatusCode != http.StatusBadRequest || cerr.SubStatus != "1001" || cerr.ActivityID != "a1b2c3" || cerr.Code != "BadRequest" {
+		t.Errorf("unexpected error: %#v", cerr)
+	}
+	if !strings.Contains(cerr.Error(), "substatus 1001") || !strings.Contains(cerr.Error(), "a1b2c3") {
+		t.Errorf("expected the substatus and activity id in the message: %s", cerr)
+	}
+	if _, ok := errors.Cause(err).(*rest.ErrorHTTPResponse); !ok {
+		t.Errorf("expected the cause to be the *rest.ErrorHTTPResponse, got %T", errors.Cause(err))
+	}
+}
+
+func TestCreateDocumentPayloadTooLarge(t *testing.T) {
+	client := testutil.NewStubClient(testutil.NewFakeRequester().
+		On(http.MethodPost, "/dbs/db1/colls/col1/docs", testutil.Status(http.StatusRequestEntityTooLarge, `{"code":"RequestEntityTooLarge","message":"Request size is too large"}`)))
+	_, _, err := client.WithDatabase("db1").WithCollection("col1").CreateDocument(context.Background(), interstellar.CreateDocumentRequest{
+		Body: []byte(`{"id":"doc1"}`),
+	})
+	if err != interstellar.ErrPayloadTooLarge {
+		t.Errorf("expected ErrPayloadTooLarge, got %v", err)
+	}
+}
@@ -19,13 +19,18 @@ package interstellar
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 const (
@@ -86,6 +91,35 @@ const (
 // HeaderDocDBIsQuery is used to indicate the POST request is a query, not a Create. Must be set to "true".
 const HeaderDocDBIsQuery = "x-ms-documentdb-isquery"
 
+// HeaderDocDBQueryIsContinuationExpected is set on a query to indicate whether the client expects (and will follow) a continuation token.
+// See Query.ContinuationExpected
+const HeaderDocDBQueryIsContinuationExpected = "x-ms-documentdb-query-iscontinuationexpected"
+
+// HeaderDocDBQueryEnableScan is set to "true" on a query to allow a scan when no suitable index exists. See Query.EnableScan
+const HeaderDocDBQueryEnableScan = "x-ms-documentdb-query-enable-scan"
+
+// HeaderDocDBResponseContinuationTokenLimitKB limits the size (in KB) of the continuation token returned by a query. See Query.ContinuationTokenLimitKB
+const HeaderDocDBResponseContinuationTokenLimitKB = "x-ms-documentdb-responsecontinuationtokenlimitinkb"
+
+// HeaderDedicatedGatewayMaxAge is the maximum staleness (in milliseconds) of a read or query result served from the integrated cache.
+// See CommonRequestOptions.MaxIntegratedCacheStaleness
+const HeaderDedicatedGatewayMaxAge = "x-ms-dedicatedgateway-max-age"
+
+// HeaderDocDBPopulateQuotaInfo is set to "true" on a collection GET to return the quota and usage of the collection
+// in the x-ms-resource-quota and x-ms-resource-usage response headers. See ResponseMetadata.Quota and ResponseMetadata.Usage
+const HeaderDocDBPopulateQuotaInfo = "x-ms-documentdb-populatequotainfo"
+
+// Replication Response Headers
+// These are returned by newer API versions and are useful when diagnosing consistency and replication lag.
+const (
+	// HeaderLSN is the local logical sequence number (LSN) of the replica which served the request.
+	HeaderLSN = "x-ms-cosmos-llsn"
+	// HeaderQuorumAckedLSN is the local LSN which has been acknowledged by a quorum of replicas.
+	HeaderQuorumAckedLSN = "x-ms-cosmos-quorum-acked-llsn"
+	// HeaderGlobalCommittedLSN is the LSN which has been committed globally, across all regions.
+	HeaderGlobalCommittedLSN = "x-ms-global-Committed-lsn"
+)
+
 // Common Response Headers
 // https://docs.microsoft.com/en-us/rest/api/cosmos-db/common-cosmosdb-rest-response-headers
 const (
@@ -128,6 +162,13 @@ const (
 	// See: https://docs.microsoft.com/azure/cosmos-db/consistency-levels
 	// See: https://docs.microsoft.com/en-us/rest/api/cosmos-db/common-cosmosdb-rest-response-headers
 	HeaderSessionToken = "x-ms-session-token"
+	// HeaderSubStatus is the sub-status code of an error response, which distinguishes errors with the same http status code
+	// See CosmosError
+	HeaderSubStatus = "x-ms-substatus"
+	// HeaderRequestDurationMS is the time (in milliseconds) the server spent processing the request. It is not returned by every API version.
+	HeaderRequestDurationMS = "x-ms-request-duration-ms"
+	// HeaderNumberOfReadRegions is the number of regions the account can serve reads from. It is only returned by some operations.
+	HeaderNumberOfReadRegions = "x-ms-number-of-read-regions"
 )
 
 // ConsistencyLevel specifies the consistency level of the operation
@@ -145,6 +186,105 @@ const (
 	ConsistencyEventual = ConsistencyLevel("Eventual")
 )
 
+// ParseConsistencyLevel parses one of the four consistency levels which may be requested: Strong, Bounded, Session, or Eventual
+// The level is matched case-insensitively, and the canonical ConsistencyLevel is returned; any other value is an error.
+// This catches typos in configured consistency levels before they are sent (and rejected) in a request.
+func ParseConsistencyLevel(s string) (ConsistencyLevel, error) {
+	for _, level := range []ConsistencyLevel{ConsistencyStrong, ConsistencyBounded, ConsistencySession, ConsistencyEventual} {
+		if strings.EqualFold(strings.TrimSpace(s), string(level)) {
+			return level, nil
+		}
+	}
+	return "", errors.Errorf("interstellar: unknown consistency level '%s'; must be one of Strong, Bounded, Session, or Eventual", s)
+}
+
+// ApplyOptions sets the consistency level header, so the level can be passed as the RequestOptions of a single operation
+func (c ConsistencyLevel) ApplyOptions(req *http.Request) {
+	if c != "" {
+		req.Header.Set(HeaderConsistencyLevel, string(c))
+	}
+}
+
+// consistencyOverride returns the consistency level, or the level of the deprecated (misspelled) ConsistencytLevel field if it is not set
+func consistencyOverride(level, deprecated ConsistencyLevel) ConsistencyLevel {
+	if level != "" {
+		return level
+	}
+	return deprecated
+}
+
+// withConsistency applies the default consistency level (if set) before the options, so the options may override it
+func withConsistency(level ConsistencyLevel, opts RequestOptions) RequestOptions {
+	if level == "" {
+		return opts
+	}
+	return mergeOptions(level, opts)
+}
+
+// UnmarshalText parses the consistency level with ParseConsistencyLevel
+// This validates consistency levels which are read from configuration files or environment variables by decoders that support encoding.TextUnmarshaler (such as encoding/json).
+func (c *ConsistencyLevel) UnmarshalText(text []byte) error {
+	level, err := ParseConsistencyLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*c = level
+	return nil
+}
+
+// BuildResourceLink builds the resource link of a resource from its path segments, which alternate between resource types and IDs, such as:
+//
+//     BuildResourceLink("dbs", "db1", "colls", "col1") // dbs/db1/colls/col1
+//
+// Each segment is escaped with url.PathEscape. The resource link is used in both the request path and the authorization signature,
+// so every client builds it here to keep them consistent; a mismatch is rejected by the server as unauthorized (401).
+// Note: Offers are the exception, and are signed with OfferResourceLink.
+func BuildResourceLink(parts ...string) string {
+	escaped := make([]string, len(parts))
+	for i, p := range parts {
+		escaped[i] = url.PathEscape(p)
+	}
+	return strings.Join(escaped, "/")
+}
+
+// OfferResourceLink is the resource link used to sign requests for an offer
+// Unlike other resources, an offer is signed with only its ID (without the "offers/" prefix), in lower case.
+func OfferResourceLink(id string) string {
+	return strings.ToLower(id)
+}
+
+// checkResourceLink checks the resource link is consistent with the path of a request for the resource type
+// The path of a feed (list, create, or query) is the resource link of the parent followed by the resource type,
+// and the path of a single resource is its resource link, which ends with the resource type and ID.
+// Offers are the exception: the resource link of an offer is its lower-cased ID.
+// Requests for other resource types (or no resource type) are not checked.
+func checkResourceLink(path string, rt ResourceType, rl string) error {
+	switch rt {
+	case ResourceDatabases, ResourceCollections, ResourceDocuments, ResourceAttachments, ResourceStoredProcedures,
+		ResourceUserDefinedFunctions, ResourceTriggers, ResourceUsers, ResourcePermissions, ResourcePartitionKeyRanges:
+	case ResourceOffers:
+		p := strings.Trim(path, "/")
+		if (p == "offers" && rl == "") || strings.EqualFold(p, "offers/"+rl) {
+			return nil
+		}
+		return errors.Errorf("interstellar: resource link '%s' does not match the path '%s' of the %s request", rl, path, rt)
+	default:
+		return nil
+	}
+	p := strings.Trim(path, "/")
+	feed := rt.String()
+	if rl != "" {
+		feed = rl + "/" + feed
+	}
+	if p == feed {
+		return nil
+	}
+	if parts := strings.Split(rl, "/"); p == rl && len(parts) >= 2 && parts[len(parts)-2] == rt.String() {
+		return nil
+	}
+	return errors.Errorf("interstellar: resource link '%s' does not match the path '%s' of the %s request", rl, path, rt)
+}
+
 // ClientRequest encapsulates the CosmosDB API request parameters
 type ClientRequest struct {
 	// Method is the HTTP Method/Verb used for the request
@@ -162,20 +302,18 @@ type ClientRequest struct {
 	// Options allow for applying additional headers and other request options to the HTTP request
 	Options RequestOptions
 	// Body is a reader which should be sent as the body of the request
+	// A *bytes.Buffer is read without being drained, so the same ClientRequest may be used to build many requests (including concurrently).
+	// Any other reader is drained by the first request built; set GetBody instead when the ClientRequest is used as a template.
 	Body io.Reader
 	// GetBody is used to set the body of the request for retrys and resubmissions
+	// If set, it is preferred over Body, and must return a new reader each time it is called.
 	GetBody func() (io.ReadCloser, error)
 }
 
-func (req *ClientRequest) readEntireBody() ([]byte, error) {
-	if req.Body != nil {
-		data, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			return nil, err
-		}
-		req.Body = bytes.NewBuffer(data)
-		return data, nil
-	}
+// bodyBytes reads the entire body without draining anything shared with other copies of the request.
+// GetBody is preferred, since it creates a new reader each time. A *bytes.Buffer Body is read without draining it,
+// and copied, so the caller may reuse the buffer once the request is built. Any other Body reader is drained, and must not be shared between concurrent requests.
+func (req *ClientRequest) bodyBytes() ([]byte, error) {
 	if req.GetBody != nil {
 		rc, err := req.GetBody()
 		if err != nil {
@@ -184,29 +322,38 @@ func (req *ClientRequest) readEntireBody() ([]byte, error) {
 		defer rc.Close()
 		return ioutil.ReadAll(rc)
 	}
+	if buf, ok := req.Body.(*bytes.Buffer); ok {
+		return append([]byte(nil), buf.Bytes()...), nil
+	}
+	if req.Body != nil {
+		return ioutil.ReadAll(req.Body)
+	}
 	return nil, nil
 }
 
+func (req *ClientRequest) readEntireBody() ([]byte, error) {
+	data, err := req.bodyBytes()
+	if err != nil || data == nil {
+		return data, err
+	}
+	req.Body = bytes.NewReader(data)
+	return data, nil
+}
+
+// reusableBody replaces the Body with a new reader which is not shared with any other request, and sets GetBody (if not already set) to re-create it
+// The ClientRequest is passed by value, so the replaced Body and GetBody are never seen by the caller.
 func (req *ClientRequest) reusableBody() error {
-	if req.Body == nil && req.GetBody != nil {
-		body, err := req.GetBody()
-		if err != nil {
-			return err
-		}
-		defer body.Close()
-		data, err := ioutil.ReadAll(body)
-		if err != nil {
-			return err
-		}
-		req.Body = bytes.NewBuffer(data)
-	} else if req.Body != nil && req.GetBody == nil {
-		data, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			return err
-		}
-		req.Body = bytes.NewBuffer(data)
+	if req.Body == nil && req.GetBody == nil {
+		return nil
+	}
+	data, err := req.bodyBytes()
+	if err != nil {
+		return err
+	}
+	req.Body = bytes.NewReader(data)
+	if req.GetBody == nil {
 		req.GetBody = func() (io.ReadCloser, error) {
-			return ioutil.NopCloser(bytes.NewBuffer(data)), nil
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
 		}
 	}
 	return nil
@@ -222,21 +369,59 @@ func (c *Client) NewHTTPRequest(ctx context.Context, req ClientRequest) (*http.R
 		return nil, err
 	}
 	hreq.GetBody = req.GetBody
-	if c.UserAgent != "" {
-		hreq.Header.Set(HeaderUserAgent, c.UserAgent)
-	} else {
-		hreq.Header.Set(HeaderUserAgent, DefaultUserAgent)
-	}
+	hreq.Header.Set(HeaderUserAgent, c.userAgent())
 	if ctx != nil {
 		hreq = hreq.WithContext(ctx)
 	}
+	if c.DefaultOptions != nil {
+		c.DefaultOptions.ApplyOptions(hreq)
+	}
 	if req.Options != nil {
 		req.Options.ApplyOptions(hreq)
 	}
+	if c.ValidateResourceLinks {
+		if err := checkResourceLink(req.Path, req.ResourceType, req.ResourceLink); err != nil {
+			return nil, err
+		}
+	}
+	if c.GenerateActivityID && hreq.Header.Get(HeaderActivityID) == "" {
+		id, err := NewActivityID()
+		if err != nil {
+			return nil, err
+		}
+		hreq.Header.Set(HeaderActivityID, id)
+	}
 	hreq, err = c.Authorizer.Authorize(hreq, req.ResourceType, req.ResourceLink)
 	return hreq, err
 }
 
+// BuildRequest builds the authorized http request for the ClientRequest without sending it; it is the same as NewHTTPRequest.
+// This is useful for a dry run, such as checking that each write in a migration would be well-formed before any of them are sent:
+//
+//     req, err := client.BuildRequest(ctx, interstellar.ClientRequest{
+//       Method:       http.MethodPost,
+//       Path:         "/dbs/db1/colls/col1/docs",
+//       ResourceType: interstellar.ResourceDocuments,
+//       ResourceLink: "dbs/db1/colls/col1",
+//       Body:         bytes.NewReader(doc),
+//     })
+//
+// Unlike the Client operations, the Method is not defaulted. See also CollectionClient.BuildCreateDocument.
+func (c *Client) BuildRequest(ctx context.Context, req ClientRequest) (*http.Request, error) {
+	return c.NewHTTPRequest(ctx, req)
+}
+
+// NewActivityID generates a random (version 4) UUID for use as the x-ms-activity-id of a request
+func NewActivityID() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", err
+	}
+	u[6] = (u[6] & 0x0f) | 0x40 // version 4
+	u[8] = (u[8] & 0x3f) | 0x80 // variant 10
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:]), nil
+}
+
 // RequestOptions augments the request, such as adding headers, or query parameter to an existing http.Request
 type RequestOptions interface {
 	ApplyOptions(req *http.Request)
@@ -263,16 +448,51 @@ func (l RequestOptionsList) ApplyOptions(req *http.Request) {
 	}
 }
 
+// addPartitionKey adds the partition key header to the request options, if the partition key is set
+func addPartitionKey(opts RequestOptions, partitionKey []string) RequestOptions {
+	if len(partitionKey) == 0 {
+		return opts
+	}
+	fn := RequestOptionsFunc(func(req *http.Request) {
+		b, _ := json.Marshal(partitionKey)
+		req.Header.Set(HeaderDocDBPartitionKey, string(b))
+	})
+	if opts == nil {
+		return fn
+	}
+	return RequestOptionsList{opts, fn}
+}
+
+// mergeOptions combines the non-nil options into one, which applies them in order
+func mergeOptions(opts ...RequestOptions) RequestOptions {
+	var list RequestOptionsList
+	for _, o := range opts {
+		if o != nil {
+			list = append(list, o)
+		}
+	}
+	switch len(list) {
+	case 0:
+		return nil
+	case 1:
+		return list[0]
+	default:
+		return list
+	}
+}
+
 // CommonRequestOptions is a helper which adds additional options to their appropriate headers in the CosmosDB HTTP request
 // The specific options which are permitted varies depending on the request
 // See: https://docs.microsoft.com/en-us/rest/api/cosmos-db/common-cosmosdb-rest-request-headers
 type CommonRequestOptions struct {
-	ActivityID                          string
-	ContentType                         string
-	IfMatch                             string
-	IfNoneMatch                         string
-	IfModifiedSince                     time.Time
-	SessionToken                        string
+	ActivityID       string
+	ContentType      string
+	IfMatch          string
+	IfNoneMatch      string
+	IfModifiedSince  time.Time
+	SessionToken     string
+	ConsistencyLevel ConsistencyLevel
+	// Deprecated: ConsistencytLevel is misspelled; use ConsistencyLevel, which takes precedence if both are set.
 	ConsistencytLevel                   ConsistencyLevel
 	DocumentDBPartitionKey              string
 	DocumentDBPartitionKeyRangeID       string
@@ -280,6 +500,13 @@ type CommonRequestOptions struct {
 	ChangeFeed                          bool
 	MaxItemCount                        int
 	Continuation                        string
+	PopulateQuotaInfo                   bool
+	// MaxIntegratedCacheStaleness is the maximum age of a cached read or query result which the integrated cache may return.
+	// The integrated cache is only used for requests sent to the dedicated gateway endpoint of the account
+	// (such as https://{account}.sqlx.cosmos.azure.com/), with Session or Eventual consistency.
+	// Use Client.WithEndpoint to send hot reads through the dedicated gateway, and other requests to the usual endpoint.
+	// The staleness is sent with millisecond precision.
+	MaxIntegratedCacheStaleness time.Duration
 }
 
 // ApplyOptions sets the common headers defined in the CommonRequestOptions struct on the given http request object
@@ -301,7 +528,7 @@ func (o *CommonRequestOptions) ApplyOptions(req *http.Request) {
 		if o.IfNoneMatch != "" {
 			req.Header.Set(HeaderIfNoneMatch, o.IfNoneMatch)
 		} else if !o.IfModifiedSince.IsZero() {
-			req.Header.Set(HeaderIfModifiedSince, o.IfModifiedSince.Format(http.TimeFormat))
+			req.Header.Set(HeaderIfModifiedSince, o.IfModifiedSince.UTC().Format(http.TimeFormat))
 		}
 	}
 	if o.DocumentDBQueryEnableCrossPartition {
@@ -313,8 +540,8 @@ func (o *CommonRequestOptions) ApplyOptions(req *http.Request) {
 	if o.SessionToken != "" {
 		req.Header.Set(HeaderSessionToken, o.SessionToken)
 	}
-	if o.ConsistencytLevel != "" {
-		req.Header.Set(HeaderConsistencyLevel, string(o.ConsistencytLevel))
+	if level := consistencyOverride(o.ConsistencyLevel, o.ConsistencytLevel); level != "" {
+		req.Header.Set(HeaderConsistencyLevel, string(level))
 	}
 	if o.Continuation != "" {
 		req.Header.Set(HeaderContinuation, o.Continuation)
@@ -331,6 +558,12 @@ func (o *CommonRequestOptions) ApplyOptions(req *http.Request) {
 	if o.ChangeFeed {
 		req.Header.Set(HeaderAIM, "Incremental feed")
 	}
+	if o.PopulateQuotaInfo {
+		req.Header.Set(HeaderDocDBPopulateQuotaInfo, "true")
+	}
+	if o.MaxIntegratedCacheStaleness > 0 {
+		req.Header.Set(HeaderDedicatedGatewayMaxAge, fmt.Sprintf("%d", o.MaxIntegratedCacheStaleness/time.Millisecond))
+	}
 }
 
 // ResponseMetadata is the parsed header values from the response
@@ -349,22 +582,109 @@ type ResponseMetadata struct {
 	SchemaVersion  string
 	ServiceVersion string
 	SessionToken   string
+
+	// PhysicalPartitionThroughputInfo is the raw x-ms-cosmos-physical-partition-throughput-info header.
+	// See ParsePhysicalPartitionThroughputInfo
+	PhysicalPartitionThroughputInfo string
+
+	// OfferReplacePending is true when an offer's throughput change has not yet been applied
+	OfferReplacePending bool
+
+	// IndexTransformationProgress is the percentage (0-100) of re-indexing which has completed after changing a collection's indexing policy
+	// It is nil when the progress is unknown, such as when the response does not report it, so it is never confused with 0% done.
+	IndexTransformationProgress *int
+
+	// LSN, QuorumAckedLSN, and GlobalCommittedLSN are the logical sequence numbers reported by the replica which served the request.
+	// They are zero when the response does not include them. See HeaderLSN, HeaderQuorumAckedLSN, and HeaderGlobalCommittedLSN
+	LSN                int64
+	QuorumAckedLSN     int64
+	GlobalCommittedLSN int64
+
+	// ServerDuration is the time the server spent processing the request, or zero if the response does not report it.
+	// The difference between this and the latency observed by the client is the time spent on the network (and in any proxies).
+	ServerDuration time.Duration
+
+	// NumberOfReadRegions is the number of read regions of the account, or zero if the response does not report it.
+	NumberOfReadRegions int
+}
+
+// ResourceCounts are the parsed values of the x-ms-resource-quota or x-ms-resource-usage headers, keyed by name.
+// For example, a collection will include documentsCount and collectionSize (in KB).
+type ResourceCounts map[string]int64
+
+// Well-known names of the ResourceCounts in the x-ms-resource-quota and x-ms-resource-usage headers
+// Sizes are in KB. For example, to get the storage used by a collection:
+//
+//     _, meta, err := cc.Get(ctx, &interstellar.CommonRequestOptions{PopulateQuotaInfo: true})
+//     used := meta.Usage()[interstellar.ResourceCountCollectionSize]
+//
+const (
+	ResourceCountDatabases        = "databases"
+	ResourceCountCollections      = "collections"
+	ResourceCountUsers            = "users"
+	ResourceCountPermissions      = "permissions"
+	ResourceCountDocumentSize     = "documentSize"
+	ResourceCountDocumentsSize    = "documentsSize"
+	ResourceCountDocumentsCount   = "documentsCount"
+	ResourceCountCollectionSize   = "collectionSize"
+	ResourceCountFunctions        = "functions"
+	ResourceCountStoredProcedures = "storedProcedures"
+	ResourceCountTriggers         = "triggers"
+)
+
+// ParseResourceCounts parses the semicolon-delimited name=value pairs of the x-ms-resource-quota or x-ms-resource-usage headers, such as:
+//
+//     functions=25;storedProcedures=100;triggers=25;documentSize=10240;documentsSize=10485760;documentsCount=-1;collectionSize=10485760;
+//
+// Pairs which are malformed or not integers are ignored.
+func ParseResourceCounts(value string) ResourceCounts {
+	counts := make(ResourceCounts)
+	for _, cmp := range strings.Split(value, ";") {
+		kv := strings.SplitN(cmp, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(kv[1]), 10, 64)
+		if err != nil {
+			continue
+		}
+		counts[strings.TrimSpace(kv[0])] = n
+	}
+	return counts
+}
+
+// Quota parses the ResourceQuota header value
+// Collections only return this when requested with the PopulateQuotaInfo option.
+func (m ResponseMetadata) Quota() ResourceCounts {
+	return ParseResourceCounts(m.ResourceQuota)
+}
+
+// Usage parses the ResourceUsage header value
+// Collections only return this when requested with the PopulateQuotaInfo option.
+func (m ResponseMetadata) Usage() ResourceCounts {
+	return ParseResourceCounts(m.ResourceUsage)
 }
 
 // GetResponseMetadata extracts response metadata from the http headers
 // And parses them into native types where applicable (such as time or numbers)
+// The Date is parsed from the Date header, or the x-ms-date header if Date is missing or is not an RFC1123 (or RFC1123Z) date.
 func GetResponseMetadata(resp *http.Response) (m ResponseMetadata) {
 	if resp == nil || resp.Header == nil {
 		return
 	}
 	hdr := resp.Header
-	if dhdr := hdr.Get(HeaderDate); dhdr != "" {
-		if date, err := time.Parse(time.RFC1123, dhdr); err == nil {
-			m.Date = date
-		}
+	if date, ok := parseHeaderDate(hdr.Get(HeaderDate)); ok {
+		m.Date = date
+	} else if date, ok = parseHeaderDate(hdr.Get(HeaderMSDate)); ok {
+		// the Date header may be stripped or rewritten by proxies
+		m.Date = date
 	}
 	m.ETag = hdr.Get(HeaderETag)
 	m.ActivityID = hdr.Get(HeaderActivityID)
+	if m.ActivityID == "" && resp.Request != nil {
+		// echo the activity id the request was sent with
+		m.ActivityID = resp.Request.Header.Get(HeaderActivityID)
+	}
 	m.AltContentPath = hdr.Get(HeaderAltContentPath)
 	m.Continuation = hdr.Get(HeaderContinuation)
 	m.RequestCharge = hdr.Get(HeaderRequestCharge)
@@ -373,11 +693,55 @@ func GetResponseMetadata(resp *http.Response) (m ResponseMetadata) {
 	m.SchemaVersion = hdr.Get(HeaderSchemaVersion)
 	m.ServiceVersion = hdr.Get(HeaderServiceVersion)
 	m.SessionToken = hdr.Get(HeaderSessionToken)
+	m.PhysicalPartitionThroughputInfo = hdr.Get(HeaderPhysicalPartitionThroughputInfo)
+	m.OfferReplacePending = strings.EqualFold(hdr.Get(HeaderOfferReplacePending), "true")
+	if hv := hdr.Get(HeaderIndexTransformationProgress); hv != "" {
+		if p, err := strconv.Atoi(hv); err == nil {
+			m.IndexTransformationProgress = &p
+		}
+	}
 	if hv := hdr.Get(HeaderItemCount); hv != "" {
 		i, err := strconv.ParseInt(hv, 10, 64)
 		if err == nil {
 			m.ItemCount = i
 		}
 	}
+	if hv := hdr.Get(HeaderRetryAfterMS); hv != "" {
+		if ms, err := strconv.ParseFloat(hv, 64); err == nil {
+			m.RetryAfterMS = time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	if hv := hdr.Get(HeaderRequestDurationMS); hv != "" {
+		if ms, err := strconv.ParseFloat(hv, 64); err == nil {
+			m.ServerDuration = time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	m.LSN = parseHeaderInt64(hdr, HeaderLSN)
+	m.QuorumAckedLSN = parseHeaderInt64(hdr, HeaderQuorumAckedLSN)
+	m.GlobalCommittedLSN = parseHeaderInt64(hdr, HeaderGlobalCommittedLSN)
+	m.NumberOfReadRegions = int(parseHeaderInt64(hdr, HeaderNumberOfReadRegions))
 	return
 }
+
+// parseHeaderDate parses an RFC1123 (or RFC1123Z) date header value
+func parseHeaderDate(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC1123, time.RFC1123Z} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseHeaderInt64 parses an integer header value, returning zero if it is missing or malformed
+func parseHeaderInt64(hdr http.Header, key string) int64 {
+	if hv := hdr.Get(key); hv != "" {
+		if i, err := strconv.ParseInt(hv, 10, 64); err == nil {
+			return i
+		}
+	}
+	return 0
+}
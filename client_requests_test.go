@@ -0,0 +1,332 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package interstellar_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jet/go-interstellar"
+	"github.com/jet/go-interstellar/internal/testutil"
+)
+
+func TestCollectionQuotaInfo(t *testing.T) {
+	hdr := make(http.Header)
+	hdr.Set(interstellar.HeaderResourceQuota, "functions=25;storedProcedures=100;triggers=25;documentSize=10240;documentsSize=10485760;documentsCount=-1;collectionSize=10485760;")
+	hdr.Set(interstellar.HeaderResourceUsage, "functions=0;storedProcedures=1;triggers=0;documentSize=0;documentsSize=2;documentsCount=42;collectionSize=3;")
+	fake := testutil.NewFakeRequester().On(http.MethodGet, "/dbs/db1/colls/col1", testutil.FakeResponse{StatusCode: http.StatusOK, Header: hdr, Body: `{"id":"col1"}`})
+	client := testutil.NewStubClient(fake)
+	_, meta, err := client.WithDatabase("db1").WithCollection("col1").Get(context.Background(), &interstellar.CommonRequestOptions{PopulateQuotaInfo: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.Requests()[0].Header.Get(interstellar.HeaderDocDBPopulateQuotaInfo) != "true" {
+		t.Errorf("expected %s header to be set", interstellar.HeaderDocDBPopulateQuotaInfo)
+	}
+	usage := meta.Usage()
+	if usage[interstellar.ResourceCountDocumentsCount] != 42 || usage[interstellar.ResourceCountCollectionSize] != 3 {
+		t.Errorf("unexpected usage: %v", usage)
+	}
+	quota := meta.Quota()
+	if quota[interstellar.ResourceCountDocumentsCount] != -1 || quota[interstellar.ResourceCountStoredProcedures] != 100 || len(quota) != 7 {
+		t.Errorf("unexpected quota: %v", quota)
+	}
+}
+
+func TestParseResourceCounts(t *testing.T) {
+	counts := interstellar.ParseResourceCounts(" databases = 100 ;bogus;name=abc;;")
+	if len(counts) != 1 || counts["databases"] != 100 {
+		t.Errorf("unexpected counts: %v", counts)
+	}
+	if counts := interstellar.ParseResourceCounts(""); len(counts) != 0 {
+		t.Errorf("expected no counts, got %v", counts)
+	}
+}
+
+func TestNewHTTPRequestConcurrentTemplate(t *testing.T) {
+	client := testutil.NewStubClient(nil)
+	const body = `{"id":"1"}`
+	templates := map[string]interstellar.ClientRequest{
+		"Body": {
+			Method: http.MethodPost,
+			Path:   "/dbs/db1/colls/col1/docs",
+			Body:   bytes.NewBufferString(body),
+		},
+		"GetBody": {
+			Method: http.MethodPost,
+			Path:   "/dbs/db1/colls/col1/docs",
+			GetBody: func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(strings.NewReader(body)), nil
+			},
+		},
+	}
+	for name, template := range templates {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					req, err := client.NewHTTPRequest(context.Background(), template)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					data, _ := ioutil.ReadAll(req.Body)
+					rc, _ := req.GetBody()
+					again, _ := ioutil.ReadAll(rc)
+					if string(data) != body || string(again) != body {
+						t.Errorf("expected body '%s', got '%s' and '%s'", body, data, again)
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestNewHTTPRequestCopiesBuffer(t *testing.T) {
+	client := testutil.NewStubClient(nil)
+	buf := bytes.NewBufferString(`{"id":"1"}`)
+	req, err := client.NewHTTPRequest(context.Background(), interstellar.ClientRequest{
+		Method: http.MethodPost,
+		Path:   "/dbs/db1/colls/col1/docs",
+		Body:   buf,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the caller reuses the buffer after building the request
+	buf.Reset()
+	buf.WriteString(`{"id":"2"}`)
+	data, _ := ioutil.ReadAll(req.Body)
+	rc, _ := req.GetBody()
+	again, _ := ioutil.ReadAll(rc)
+	if string(data) != `{"id":"1"}` || string(again) != `{"id":"1"}` {
+		t.Errorf("expected the request body to be unaffected by the buffer, got '%s' and '%s'", data, again)
+	}
+}
+
+func TestGenerateActivityID(t *testing.T) {
+	fake := testutil.NewFakeRequester().On(http.MethodGet, "/dbs/db1", testutil.Page(`{"id":"db1"}`, ""))
+	client := testutil.NewStubClient(fake)
+	client.GenerateActivityID = true
+	db := client.WithDatabase("db1")
+	_, meta, err := db.Get(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := []string{fake.Requests()[0].Header.Get(interstellar.HeaderActivityID)}
+	if len(sent[0]) != 36 || sent[0][14] != '4' {
+		t.Errorf("expected a version 4 UUID, got '%s'", sent[0])
+	}
+	if meta.ActivityID != sent[0] {
+		t.Errorf("expected activity id '%s' to be echoed, got '%s'", sent[0], meta.ActivityID)
+	}
+	if _, meta, err = db.Get(context.Background(), &interstellar.CommonRequestOptions{ActivityID: "caller-id"}); err != nil {
+		t.Fatal(err)
+	}
+	sent = append(sent, fake.Requests()[1].Header.Get(interstellar.HeaderActivityID))
+	if sent[1] != "caller-id" || meta.ActivityID != "caller-id" {
+		t.Errorf("expected the caller's activity id to be kept, got '%s'", sent[1])
+	}
+}
+
+func TestResponseMetadataLSN(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set(interstellar.HeaderLSN, "42")
+	resp.Header.Set(interstellar.HeaderQuorumAckedLSN, "41")
+	resp.Header.Set(interstellar.HeaderGlobalCommittedLSN, "40")
+	meta := interstellar.GetResponseMetadata(resp)
+	if meta.LSN != 42 || meta.QuorumAckedLSN != 41 || meta.GlobalCommittedLSN != 40 {
+		t.Errorf("unexpected LSNs: %d %d %d", meta.LSN, meta.QuorumAckedLSN, meta.GlobalCommittedLSN)
+	}
+	meta = interstellar.GetResponseMetadata(&http.Response{Header: http.Header{}})
+	if meta.LSN != 0 || meta.QuorumAckedLSN != 0 || meta.GlobalCommittedLSN != 0 {
+		t.Errorf("expected zero LSNs when headers are missing")
+	}
+}
+
+func TestResponseMetadataDate(t *testing.T) {
+	expected := time.Date(2019, time.March, 4, 5, 6, 7, 0, time.UTC)
+	tests := []struct {
+		name   string
+		date   string
+		msDate string
+		zero   bool
+	}{
+		{name: "RFC1123", date: "Mon, 04 Mar 2019 05:06:07 GMT"},
+		{name: "RFC1123Z", date: "Mon, 04 Mar 2019 05:06:07 +0000"},
+		{name: "missing", msDate: "Mon, 04 Mar 2019 05:06:07 GMT"},
+		{name: "unparseable", date: "2019-03-04", msDate: "Mon, 04 Mar 2019 05:06:07 GMT"},
+		{name: "neither", date: "2019-03-04", msDate: "yesterday", zero: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if test.date != "" {
+				resp.Header.Set(interstellar.HeaderDate, test.date)
+			}
+			if test.msDate != "" {
+				resp.Header.Set(interstellar.HeaderMSDate, test.msDate)
+			}
+			meta := interstellar.GetResponseMetadata(resp)
+			if test.zero {
+				if !meta.Date.IsZero() {
+					t.Errorf("expected zero date, got %v", meta.Date)
+				}
+				return
+			}
+			if !meta.Date.Equal(expected) {
+				t.Errorf("expected %v, got %v", expected, meta.Date)
+			}
+		})
+	}
+}
+
+func TestParseConsistencyLevel(t *testing.T) {
+	tests := map[string]interstellar.ConsistencyLevel{
+		"Strong":    interstellar.ConsistencyStrong,
+		"bounded":   interstellar.ConsistencyBounded,
+		" SESSION ": interstellar.ConsistencySession,
+		"Eventual":  interstellar.ConsistencyEventual,
+		"Strongish": "",
+		"":          "",
+	}
+	for s, expected := range tests {
+		level, err := interstellar.ParseConsistencyLevel(s)
+		if expected == "" {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %s", s, level)
+			}
+			continue
+		}
+		if err != nil || level != expected {
+			t.Errorf("%q: expected %s, got %s (%v)", s, expected, level, err)
+		}
+	}
+	var config struct {
+		Consistency interstellar.ConsistencyLevel `json:"consistency"`
+	}
+	if err := json.Unmarshal([]byte(`{"consistency":"session"}`), &config); err != nil || config.Consistency != interstellar.ConsistencySession {
+		t.Errorf("expected Session, got %s (%v)", config.Consistency, err)
+	}
+	if err := json.Unmarshal([]byte(`{"consistency":"Strongish"}`), &config); err == nil {
+		t.Error("expected an error decoding an unknown consistency level")
+	}
+}
+
+func TestBuildResourceLink(t *testing.T) {
+	tests := map[string]string{
+		interstellar.BuildResourceLink("dbs", "db1"):                                  "dbs/db1",
+		interstellar.BuildResourceLink("dbs", "db1", "colls", "col 1", "docs", "a/b"): "dbs/db1/colls/col%201/docs/a%2Fb",
+		interstellar.OfferResourceLink("Hu+t"):                                        "hu+t",
+	}
+	for actual, expected := range tests {
+		if actual != expected {
+			t.Errorf("expected %s, got %s", expected, actual)
+		}
+	}
+	cc := interstellar.Client{}
+	if rl := cc.WithDatabase("db 1").WithCollection("col1").WithDocument("doc/1", nil).ResourceLink(); rl != "dbs/db%201/colls/col1/docs/doc%2F1" {
+		t.Errorf("unexpected document resource link: %s", rl)
+	}
+}
+
+func TestMaxIntegratedCacheStaleness(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://account.sqlx.cosmos.azure.com/dbs/db1/colls/col1/docs/doc1", nil)
+	opts := &interstellar.CommonRequestOptions{MaxIntegratedCacheStaleness: 90 * time.Second}
+	opts.ApplyOptions(req)
+	if actual := req.Header.Get(interstellar.HeaderDedicatedGatewayMaxAge); actual != "90000" {
+		t.Errorf("expected max age 90000, got '%s'", actual)
+	}
+	req, _ = http.NewRequest(http.MethodGet, "https://account.sqlx.cosmos.azure.com/dbs/db1/colls/col1/docs/doc1", nil)
+	(&interstellar.CommonRequestOptions{}).ApplyOptions(req)
+	if _, ok := req.Header[http.CanonicalHeaderKey(interstellar.HeaderDedicatedGatewayMaxAge)]; ok {
+		t.Errorf("expected no max age header by default")
+	}
+}
+
+func TestValidateResourceLinks(t *testing.T) {
+	client := testutil.NewStubClient(nil)
+	client.ValidateResourceLinks = true
+	tests := []struct {
+		name string
+		path string
+		rt   interstellar.ResourceType
+		rl   string
+		ok   bool
+	}{
+		{name: "database feed", path: "/dbs", rt: interstellar.ResourceDatabases, rl: "", ok: true},
+		{name: "database", path: "/dbs/db1", rt: interstellar.ResourceDatabases, rl: "dbs/db1", ok: true},
+		{name: "document feed", path: "/dbs/db1/colls/col1/docs", rt: interstellar.ResourceDocuments, rl: "dbs/db1/colls/col1", ok: true},
+		{name: "document", path: "/dbs/db1/colls/col1/docs/doc1", rt: interstellar.ResourceDocuments, rl: "dbs/db1/colls/col1/docs/doc1", ok: true},
+		{name: "offer", path: "/offers/HU+T", rt: interstellar.ResourceOffers, rl: "hu+t", ok: true},
+		{name: "offer feed", path: "/offers", rt: interstellar.ResourceOffers, rl: "", ok: true},
+		{name: "unknown type", path: "/dbs/db1/colls/col1/operations/partitionkeydelete", rt: interstellar.ResourcePartitionKey, rl: "dbs/db1/colls/col1", ok: true},
+		{name: "document feed with document link", path: "/dbs/db1/colls/col1/docs", rt: interstellar.ResourceDocuments, rl: "dbs/db1/colls/col1/docs/doc1"},
+		{name: "document with collection link", path: "/dbs/db1/colls/col1/docs/doc1", rt: interstellar.ResourceDocuments, rl: "dbs/db1/colls/col1"},
+		{name: "wrong type", path: "/dbs/db1/colls/col1", rt: interstellar.ResourceDocuments, rl: "dbs/db1/colls/col1"},
+		{name: "wrong offer", path: "/offers/abc", rt: interstellar.ResourceOffers, rl: "xyz"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := client.NewHTTPRequest(nil, interstellar.ClientRequest{
+				Method:       http.MethodGet,
+				Path:         test.path,
+				ResourceType: test.rt,
+				ResourceLink: test.rl,
+			})
+			if test.ok && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !test.ok && err == nil {
+				t.Error("expected a mismatched resource link to be an error")
+			}
+		})
+	}
+}
+
+func TestResponseMetadataServerDuration(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set(interstellar.HeaderRequestDurationMS, "12.5")
+	if meta := interstellar.GetResponseMetadata(resp); meta.ServerDuration != 12500*time.Microsecond {
+		t.Errorf("expected 12.5ms, got %v", meta.ServerDuration)
+	}
+	if meta := interstellar.GetResponseMetadata(&http.Response{Header: http.Header{}}); meta.ServerDuration != 0 {
+		t.Errorf("expected no server duration when the header is missing, got %v", meta.ServerDuration)
+	}
+}
+
+func TestResponseMetadataNumberOfReadRegions(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set(interstellar.HeaderNumberOfReadRegions, "3")
+	if meta := interstellar.GetResponseMetadata(resp); meta.NumberOfReadRegions != 3 {
+		t.Errorf("expected 3 read regions, got %d", meta.NumberOfReadRegions)
+	}
+	if meta := interstellar.GetResponseMetadata(&http.Response{Header: http.Header{}}); meta.NumberOfReadRegions != 0 {
+		t.Errorf("expected no read regions when the header is missing, got %d", meta.NumberOfReadRegions)
+	}
+}
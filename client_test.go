@@ -17,9 +17,17 @@
 package interstellar_test
 
 import (
+	"context"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
 
 	"github.com/jet/go-interstellar"
+	"github.com/jet/go-interstellar/internal/testutil"
+	"github.com/jet/go-mantis/rest"
 )
 
 func ExampleClient_custom() {
@@ -37,3 +45,238 @@ func ExampleNewClient() {
 	cs, _ := interstellar.ParseConnectionString(cstring)
 	_, _ = interstellar.NewClient(cs, nil)
 }
+
+func TestClientWithUserAgentSuffix(t *testing.T) {
+	client := testutil.NewStubClient(nil)
+	client.UserAgent = ""
+	suffixed := client.WithUserAgentSuffix("MyLibrary/2.0").WithUserAgentSuffix("MyApp/1.0")
+	req, err := suffixed.NewHTTPRequest(nil, interstellar.ClientRequest{Method: http.MethodGet, Path: "/dbs", ResourceType: interstellar.ResourceDatabases})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := interstellar.DefaultUserAgent + " MyLibrary/2.0 MyApp/1.0"
+	if ua := req.Header.Get(interstellar.HeaderUserAgent); ua != expected {
+		t.Errorf("expected user agent '%s', got '%s'", expected, ua)
+	}
+	if client.UserAgent != "" {
+		t.Errorf("expected the original client to be unchanged, got '%s'", client.UserAgent)
+	}
+}
+
+func TestClientShortcuts(t *testing.T) {
+	client := testutil.NewStubClient(nil)
+	if rl := client.Collection("db1", "col1").ResourceLink(); rl != "dbs/db1/colls/col1" {
+		t.Errorf("unexpected collection resource link '%s'", rl)
+	}
+	dc := client.Document("db1", "col1", "doc 1", []string{"pk"})
+	if rl := dc.ResourceLink(); rl != "dbs/db1/colls/col1/docs/doc%201" {
+		t.Errorf("unexpected document resource link '%s'", rl)
+	}
+	if len(dc.PartitionKey) != 1 || dc.PartitionKey[0] != "pk" {
+		t.Errorf("unexpected partition key %v", dc.PartitionKey)
+	}
+}
+
+type closeRecordingTransport struct {
+	http.RoundTripper
+	closed bool
+}
+
+func (t *closeRecordingTransport) CloseIdleConnections() {
+	t.closed = true
+}
+
+func ExampleNewClientWithOptions() {
+	cstring := "AccountEndpoint=https://localhost:8081/;AccountKey=C2y6yDjf5/R+ob0N8A7Cgv30VRDJIWEHLM+4QDU5DE2nQ9nDuVTqobD4b8mGGyPMbIZnqyMsEcaGQy67XIw/Jw=="
+	cs, _ := interstellar.ParseConnectionString(cstring)
+	_, _ = interstellar.NewClientWithOptions(cs, nil, interstellar.ClientOptions{
+		RetryStatusCodes: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable},
+	})
+}
+
+func TestNewClientWithOptions(t *testing.T) {
+	cs, err := interstellar.ParseConnectionString("AccountEndpoint=https://localhost:8081/;AccountKey=C2y6yDjf5/R+ob0N8A7Cgv30VRDJIWEHLM+4QDU5DE2nQ9nDuVTqobD4b8mGGyPMbIZnqyMsEcaGQy67XIw/Jw==")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := interstellar.NewClientWithOptions(cs, http.DefaultClient, interstellar.ClientOptions{
+		RetryStatusCodes: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable},
+		RetryHeaderName:  "x-retry-after",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	retry, ok := client.Requester.(*rest.RetryAfterRequester)
+	if !ok {
+		t.Fatalf("expected a RetryAfterRequester, got %T", client.Requester)
+	}
+	if len(retry.StatusCodes) != 2 || retry.StatusCodes[1] != http.StatusServiceUnavailable {
+		t.Errorf("unexpected retry status codes: %v", retry.StatusCodes)
+	}
+	if retry.HeaderName != "x-retry-after" {
+		t.Errorf("unexpected retry header name: %s", retry.HeaderName)
+	}
+	transient, ok := retry.Requester.(*interstellar.TransientRetryRequester)
+	if !ok || transient.MaxRetries != interstellar.DefaultTransientRetries || transient.Backoff != interstellar.DefaultTransientBackoff {
+		t.Errorf("expected the default transient retries, got %#v", retry.Requester)
+	}
+
+	client, err = interstellar.NewClientWithOptions(cs, http.DefaultClient, interstellar.ClientOptions{
+		TransientRetries: 5,
+		TransientBackoff: time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transient, ok = client.Requester.(*rest.RetryAfterRequester).Requester.(*interstellar.TransientRetryRequester)
+	if !ok || transient.MaxRetries != 5 || transient.Backoff != time.Second {
+		t.Errorf("expected the configured transient retries, got %#v", client.Requester.(*rest.RetryAfterRequester).Requester)
+	}
+
+	client, err = interstellar.NewClientWithOptions(cs, http.DefaultClient, interstellar.ClientOptions{TransientRetries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req := client.Requester.(*rest.RetryAfterRequester).Requester; req != http.DefaultClient {
+		t.Errorf("expected transient retries to be disabled, got %#v", req)
+	}
+}
+
+func TestClientClose(t *testing.T) {
+	cs, err := interstellar.ParseConnectionString("AccountEndpoint=https://localhost:8081/;AccountKey=C2y6yDjf5/R+ob0N8A7Cgv30VRDJIWEHLM+4QDU5DE2nQ9nDuVTqobD4b8mGGyPMbIZnqyMsEcaGQy67XIw/Jw==")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := make(chan struct{}, 1)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"db1"}`))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			select {
+			case closed <- struct{}{}:
+			default:
+			}
+		}
+	}
+	server.Start()
+	defer server.Close()
+	cs.Endpoint = server.URL
+	owned, err := interstellar.NewClient(cs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = owned.WithDatabase("db1").GetRaw(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = owned.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the idle connection of the owned http client to be closed")
+	}
+	transport := &closeRecordingTransport{}
+	supplied, err := interstellar.NewClient(cs, &http.Client{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = supplied.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if transport.closed {
+		t.Error("expected a supplied requester not to be closed")
+	}
+}
+
+func TestClientWithOptions(t *testing.T) {
+	fake := testutil.NewFakeRequester().On(http.MethodGet, "/dbs/db1/colls/col1/docs/doc1", testutil.Page(`{"id":"doc1"}`, ""))
+	client := testutil.NewStubClient(fake)
+	cc := client.WithDatabase("db1").WithCollection("col1").WithOptions(&interstellar.CommonRequestOptions{
+		ConsistencyLevel: interstellar.ConsistencySession,
+		SessionToken:     "token1",
+	})
+	doc := cc.WithDocument("doc1", nil)
+	if _, _, err := doc.GetRaw(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := doc.GetRaw(context.Background(), &interstellar.CommonRequestOptions{SessionToken: "token2"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.WithDatabase("db1").WithCollection("col1").WithDocument("doc1", nil).GetRaw(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct{ consistency, session string }{
+		{"Session", "token1"},
+		{"Session", "token2"},
+		{"", ""},
+	}
+	reqs := fake.Requests()
+	for i, ex := range expected {
+		if c := reqs[i].Header.Get(interstellar.HeaderConsistencyLevel); c != ex.consistency {
+			t.Errorf("request %d: expected consistency %q, got %q", i, ex.consistency, c)
+		}
+		if s := reqs[i].Header.Get(interstellar.HeaderSessionToken); s != ex.session {
+			t.Errorf("request %d: expected session token %q, got %q", i, ex.session, s)
+		}
+	}
+}
+
+func TestClientMaxConcurrentRequests(t *testing.T) {
+	var mu sync.Mutex
+	var inFlight, peak int
+	unblock := make(chan struct{})
+	client := testutil.NewStubClient(testutil.RequesterFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		mu.Unlock()
+		<-unblock
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return testutil.NewResponse(req, http.StatusOK, nil, `{"id":"db1"}`), nil
+	}))
+	unlimited := client
+	client = client.WithMaxConcurrentRequests(2)
+	west := client.WithEndpoint("https://account-westus.documents.azure.com")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 6)
+	for i := 0; i < 6; i++ {
+		c := client
+		if i%2 == 1 {
+			c = west
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := c.WithDatabase("db1").GetRaw(context.Background(), nil)
+			errs <- err
+		}()
+	}
+	// with the limit reached, a request waits until its context is done
+	time.Sleep(50 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := client.WithDatabase("db1").GetRaw(ctx, nil); err != context.DeadlineExceeded {
+		t.Errorf("expected the waiting request to fail with the context's error, got %v", err)
+	}
+	close(unblock)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if peak != 2 {
+		t.Errorf("expected at most 2 requests in flight, got %d", peak)
+	}
+	if unlimited.MaxConcurrentRequests() != 0 || client.MaxConcurrentRequests() != 2 || west.MaxConcurrentRequests() != 2 {
+		t.Errorf("unexpected limits %d, %d and %d", unlimited.MaxConcurrentRequests(), client.MaxConcurrentRequests(), west.MaxConcurrentRequests())
+	}
+}
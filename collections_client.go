@@ -22,7 +22,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"net/url"
 )
 
 const (
@@ -30,6 +29,11 @@ const (
 	HeaderOfferType = "x-ms-offer-type"
 	// HeaderOfferThroughput is used to set the provisioned RU Throughput on the collection at creation time.
 	HeaderOfferThroughput = "x-ms-offer-throughput"
+	// HeaderIndexTransformationProgress is the progress (0-100) of re-indexing after a collection's indexing policy is changed.
+	// It is returned when getting the collection. See ResponseMetadata.IndexTransformationProgress
+	HeaderIndexTransformationProgress = "x-ms-documentdb-collection-index-transformation-progress"
+	// HeaderPopulatePartitionStatistics is set to "true" on a collection GET to include the partitionKeyRangeStatistics of each partition key range in the body
+	HeaderPopulatePartitionStatistics = "x-ms-documentdb-populatepartitionstatistics"
 )
 
 // CollectionClient is a client scoped to a single collection
@@ -38,6 +42,25 @@ type CollectionClient struct {
 	Client       *Client
 	DatabaseID   string
 	CollectionID string
+
+	// IndexingDirective is the default indexing directive for documents created (or upserted) with this client
+	// It is overridden by CreateDocumentRequest.IndexingDirective, and inherited by WithDocument.
+	// For example, set it to DocumentIndexingExclude to save RU on bulk loads.
+	IndexingDirective *DocumentIndexingDirective
+
+	// ConsistencyLevel is the default consistency level of the documents read, listed, or queried with this client
+	// It is overridden by the consistency level of the options (or Query) of each operation, and inherited by WithDocument.
+	ConsistencyLevel ConsistencyLevel
+
+	// Partitioned indicates the collection has a partition key, so documents cannot be replaced without one
+	// It is inherited by WithDocument; set it from the collection definition, such as CollectionResource.PartitionKey != nil.
+	Partitioned bool
+
+	// StripSystemProperties removes the SystemProperties (such as _rid and _ts) from the documents read, listed, queried,
+	// or read from the change feed with this client, for a leaner representation. It is inherited by WithDocument.
+	// The server always returns the system properties, so they are removed by the client (see StripSystemProperties).
+	// Queries which project their own properties (anything but SELECT *) are returned as is, so a projected _ts or _etag is kept.
+	StripSystemProperties bool
 }
 
 // WithCollection creates a CollectionClient for the given Collection within this Database
@@ -49,9 +72,44 @@ func (c *DatabaseClient) WithCollection(id string) *CollectionClient {
 	}
 }
 
+// Collection creates a CollectionClient for the given Collection within the given Database
+// This is a shortcut for client.WithDatabase(databaseID).WithCollection(collectionID)
+func (c *Client) Collection(databaseID, collectionID string) *CollectionClient {
+	return c.WithDatabase(databaseID).WithCollection(collectionID)
+}
+
+// InRegion creates a copy of the CollectionClient which sends its requests to the given regional endpoint of the account
+// For example, to pin latency-sensitive queries to the nearest region:
+//
+//     west := cc.InRegion("https://myaccount-westus.documents.azure.com:443/")
+//
+// See Client.WithEndpoint
+func (c *CollectionClient) InRegion(endpoint string) *CollectionClient {
+	cc := *c
+	cc.Client = c.Client.WithEndpoint(endpoint)
+	return &cc
+}
+
+// InWriteRegion creates a copy of the CollectionClient which sends its requests to the write endpoint of the named region
+// This pins writes to a region, such as for data residency:
+//
+//     account, _, err := client.GetAccount(ctx, nil)
+//     eu, err := cc.InWriteRegion(account, "West Europe")
+//     _, _, err = eu.CreateDocument(ctx, req)
+//
+// Writes to regions other than the primary write region require an account with multi-region writes (multi-master).
+// Requests are authorized the same way in every region. See AccountProperties.WriteEndpoint
+func (c *CollectionClient) InWriteRegion(account *AccountProperties, region string) (*CollectionClient, error) {
+	endpoint, err := account.WriteEndpoint(region)
+	if err != nil {
+		return nil, err
+	}
+	return c.InRegion(endpoint), nil
+}
+
 // ResourceLink gets the resource link for the collection
 func (c *CollectionClient) ResourceLink() string {
-	return fmt.Sprintf("dbs/%s/colls/%s", url.PathEscape(c.DatabaseID), url.PathEscape(c.CollectionID))
+	return BuildResourceLink("dbs", c.DatabaseID, "colls", c.CollectionID)
 }
 
 // ListCollectionsRaw lists each collection in the database as raw JSON objects
@@ -85,12 +143,14 @@ func (c *DatabaseClient) ListCollections(ctx context.Context, opts RequestOption
 
 // CreateCollectionRequest captures the request options for creating a new Collection
 type CreateCollectionRequest struct {
-	OfferThroughput int                       `json:"-"`
-	OfferType       OfferType                 `json:"-"`
-	Options         RequestOptions            `json:"-"`
-	ID              string                    `json:"id"`
-	IndexingPolicy  *CollectionIndexingPolicy `json:"indexingPolicy,omitempty"`
-	PartitionKey    *CollectionPartitionKey   `json:"partitionKey,omitempty"`
+	OfferThroughput       int                       `json:"-"`
+	OfferType             OfferType                 `json:"-"`
+	Options               RequestOptions            `json:"-"`
+	ID                    string                    `json:"id"`
+	IndexingPolicy        *CollectionIndexingPolicy `json:"indexingPolicy,omitempty"`
+	PartitionKey          *CollectionPartitionKey   `json:"partitionKey,omitempty"`
+	VectorEmbeddingPolicy *VectorEmbeddingPolicy    `json:"vectorEmbeddingPolicy,omitempty"`
+	GeospatialConfig      *GeospatialConfig         `json:"geospatialConfig,omitempty"`
 }
 
 // ApplyOptions applies additional headers necessary to complete a CreateCollection request
@@ -134,6 +194,50 @@ func (c *DatabaseClient) CreateCollection(ctx context.Context, req CreateCollect
 	return &coll, meta, err
 }
 
+// CollectionExists checks if the collection with the given ID exists in the database
+func (c *DatabaseClient) CollectionExists(ctx context.Context, id string) (bool, error) {
+	_, _, err := c.WithCollection(id).GetRaw(ctx, nil)
+	switch err {
+	case nil:
+		return true, nil
+	case ErrResourceNotFound:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// CreateCollectionIfNotExists gets the collection with the request ID, or creates it if it does not exist
+// Returns true if the collection was created.
+// Note: An existing collection is returned as-is; it is not updated to match the request.
+// If the collection is created concurrently by someone else, the conflict is handled by getting the collection again.
+func (c *DatabaseClient) CreateCollectionIfNotExists(ctx context.Context, req CreateCollectionRequest) (*CollectionResource, bool, *ResponseMetadata, error) {
+	coll, meta, err := c.WithCollection(req.ID).Get(ctx, nil)
+	if err != ErrResourceNotFound {
+		return coll, false, meta, err
+	}
+	coll, meta, err = c.CreateCollection(ctx, req)
+	if err == ErrResourceConflict {
+		coll, meta, err = c.WithCollection(req.ID).Get(ctx, nil)
+		return coll, false, meta, err
+	}
+	if err != nil {
+		return nil, false, meta, err
+	}
+	return coll, true, meta, nil
+}
+
+// EnsureCollection creates the collection, or gets it if it already exists
+// Unlike CreateCollectionIfNotExists, the create is attempted first; a conflict with a concurrent creator is handled by getting the existing collection.
+// Note: An existing collection is returned as-is; it is not updated to match the request.
+func (c *DatabaseClient) EnsureCollection(ctx context.Context, req CreateCollectionRequest) (*CollectionResource, *ResponseMetadata, error) {
+	coll, meta, err := c.CreateCollection(ctx, req)
+	if err == ErrResourceConflict {
+		return c.WithCollection(req.ID).Get(ctx, nil)
+	}
+	return coll, meta, err
+}
+
 // GetRaw retrieves the raw collection
 func (c *CollectionClient) GetRaw(ctx context.Context, opts RequestOptions) ([]byte, *ResponseMetadata, error) {
 	rl := c.ResourceLink()
@@ -158,6 +262,139 @@ func (c *CollectionClient) Get(ctx context.Context, opts RequestOptions) (*Colle
 	return &coll, meta, err
 }
 
+// ReplaceRaw replaces the collection's indexing policy with the one on the given collection and returns the raw response
+// The ID and PartitionKey of the collection cannot be changed, but must match the existing collection.
+// If the collection has an ETag (such as from Get), the replace is conditional on it (If-Match), so a concurrent change to
+// the collection fails with ErrPreconditionFailed instead of being overwritten. An IfMatch in the options takes precedence.
+// Only the properties of the CollectionResource are sent, so properties it does not model (such as defaultTtl) are reset;
+// use SetIndexingModeLazy to change the indexing mode alone.
+func (c *CollectionClient) ReplaceRaw(ctx context.Context, coll *CollectionResource, opts RequestOptions) ([]byte, *ResponseMetadata, error) {
+	req, err := coll.ToCreateRequest()
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := json.Marshal(&req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.replaceBody(ctx, body, coll.ETag, opts)
+}
+
+// replaceBody replaces the collection with the given JSON document, conditional on the etag if it is not empty
+func (c *CollectionClient) replaceBody(ctx context.Context, body []byte, etag string, opts RequestOptions) ([]byte, *ResponseMetadata, error) {
+	rl := c.ResourceLink()
+	if etag != "" {
+		opts = mergeOptions(&CommonRequestOptions{IfMatch: etag}, opts)
+	}
+	return c.Client.CreateOrReplaceResource(ctx, ClientRequest{
+		Method:       http.MethodPut,
+		Path:         fmt.Sprintf("/%s", rl),
+		ResourceLink: rl,
+		ResourceType: ResourceCollections,
+		Options:      opts,
+		Body:         bytes.NewBuffer(body),
+	})
+}
+
+// Replace replaces the collection's indexing policy with the one on the given collection
+func (c *CollectionClient) Replace(ctx context.Context, coll *CollectionResource, opts RequestOptions) (*CollectionResource, *ResponseMetadata, error) {
+	body, meta, err := c.ReplaceRaw(ctx, coll, opts)
+	if err != nil {
+		return nil, meta, err
+	}
+	var ncoll CollectionResource
+	if err = json.Unmarshal(body, &ncoll); err != nil {
+		return nil, meta, err
+	}
+	return &ncoll, meta, err
+}
+
+// SetIndexingModeLazy retrieves the collection, switches its indexing policy to the Lazy indexing mode, and replaces it.
+// On success, the LazyIndexingWarning is returned along with the updated collection.
+// Only the indexingMode is changed; every other property of the collection is sent back as it was read, and the replace is
+// conditional on the ETag that was read.
+//
+// Lazy indexing is eventually consistent: documents written to the collection may not be returned by queries until the index catches up.
+// Callers which depend on reading their own writes through queries should not use this mode.
+func (c *CollectionClient) SetIndexingModeLazy(ctx context.Context, opts RequestOptions) (*CollectionResource, *ResponseMetadata, string, error) {
+	raw, meta, err := c.GetRaw(ctx, opts)
+	if err != nil {
+		return nil, meta, "", err
+	}
+	// the collection is kept as raw JSON, so properties which CollectionResource does not model are not lost
+	var doc map[string]json.RawMessage
+	if err = json.Unmarshal(raw, &doc); err != nil {
+		return nil, meta, "", err
+	}
+	policy := map[string]json.RawMessage{}
+	if data, ok := doc["indexingPolicy"]; ok && string(data) != "null" {
+		if err = json.Unmarshal(data, &policy); err != nil {
+			return nil, meta, "", err
+		}
+	}
+	if policy["indexingMode"], err = json.Marshal(IndexingModeLazy); err != nil {
+		return nil, meta, "", err
+	}
+	if doc["indexingPolicy"], err = json.Marshal(policy); err != nil {
+		return nil, meta, "", err
+	}
+	var etag string
+	if data, ok := doc["_etag"]; ok {
+		if err = json.Unmarshal(data, &etag); err != nil {
+			return nil, meta, "", err
+		}
+	}
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return nil, meta, "", err
+	}
+	body, meta, err = c.replaceBody(ctx, body, etag, opts)
+	if err != nil {
+		return nil, meta, "", err
+	}
+	var coll CollectionResource
+	if err = json.Unmarshal(body, &coll); err != nil {
+		return nil, meta, "", err
+	}
+	return &coll, meta, LazyIndexingWarning, nil
+}
+
+// EstimateDocumentCount estimates the number of documents in the collection from its statistics, which is much cheaper than a COUNT query
+// The collection is read with its partition key range statistics, and the document count of each range is summed.
+// If the statistics are not returned, the documentsCount usage of the collection is used instead (see ResponseMetadata.Usage).
+// The statistics are updated periodically by the server, so the estimate may lag behind recent writes.
+func (c *CollectionClient) EstimateDocumentCount(ctx context.Context) (int64, error) {
+	body, meta, err := c.GetRaw(ctx, RequestOptionsList{
+		&CommonRequestOptions{PopulateQuotaInfo: true},
+		RequestOptionsFunc(func(req *http.Request) {
+			req.Header.Set(HeaderPopulatePartitionStatistics, "true")
+		}),
+	})
+	if err != nil {
+		return 0, err
+	}
+	var stats struct {
+		PartitionKeyRangeStatistics []struct {
+			DocumentCount int64 `json:"documentCount"`
+		} `json:"partitionKeyRangeStatistics"`
+	}
+	if err = json.Unmarshal(body, &stats); err != nil {
+		return 0, err
+	}
+	if len(stats.PartitionKeyRangeStatistics) == 0 {
+		count, ok := meta.Usage()[ResourceCountDocumentsCount]
+		if !ok {
+			return 0, Error("interstellar: collection did not return any document count statistics")
+		}
+		return count, nil
+	}
+	var count int64
+	for _, pkr := range stats.PartitionKeyRangeStatistics {
+		count += pkr.DocumentCount
+	}
+	return count, nil
+}
+
 // Delete will delete the collection
 // See Client.DeleteResource for more information
 func (c *CollectionClient) Delete(ctx context.Context, opts RequestOptions) (bool, *ResponseMetadata, error) {
@@ -169,3 +406,21 @@ func (c *CollectionClient) Delete(ctx context.Context, opts RequestOptions) (boo
 		Options:      opts,
 	})
 }
+
+// DeleteIfExists will delete the collection, and treats a collection which does not exist as already deleted
+// Returns false (and no error) if the collection did not exist.
+func (c *CollectionClient) DeleteIfExists(ctx context.Context, opts RequestOptions) (bool, *ResponseMetadata, error) {
+	ok, meta, err := c.Delete(ctx, opts)
+	if err == ErrResourceNotFound {
+		return false, meta, nil
+	}
+	return ok, meta, err
+}
+
+// WithOptions creates a copy of the CollectionClient which applies the options to every request made by it
+// See Client.WithOptions
+func (c *CollectionClient) WithOptions(opts RequestOptions) *CollectionClient {
+	cc := *c
+	cc.Client = c.Client.WithOptions(opts)
+	return &cc
+}
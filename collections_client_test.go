@@ -19,8 +19,12 @@ package interstellar_test
 import (
 	"context"
 	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
 
 	"github.com/jet/go-interstellar"
+	"github.com/jet/go-interstellar/internal/testutil"
 )
 
 func ExampleCollectionClient_QueryDocumentsRaw() {
@@ -66,3 +70,230 @@ func ExampleCollectionClient_QueryDocumentsRaw() {
 		return true, nil
 	})
 }
+
+func TestCollectionClientSetIndexingModeLazy(t *testing.T) {
+	// the properties which CollectionResource does not model must be sent back unchanged
+	expected := `{"_etag":"\"1\"","_rid":"PaYSAPH7qAo=","conflictResolutionPolicy":{"mode":"LastWriterWins","conflictResolutionPath":"/_ts"},"defaultTtl":3600,"id":"col1","indexingPolicy":{"automatic":true,"indexingMode":"Lazy"},"partitionKey":{"paths":["/id"],"kind":"Hash"},"uniqueKeyPolicy":{"uniqueKeys":[{"paths":["/name"]}]}}`
+	fake := testutil.NewFakeRequester().
+		On(http.MethodGet, "/dbs/db1/colls/col1", testutil.Page(`{"id":"col1","indexingPolicy":{"automatic":true,"indexingMode":"Consistent"},"partitionKey":{"paths":["/id"],"kind":"Hash"},"defaultTtl":3600,"uniqueKeyPolicy":{"uniqueKeys":[{"paths":["/name"]}]},"conflictResolutionPolicy":{"mode":"LastWriterWins","conflictResolutionPath":"/_ts"},"_rid":"PaYSAPH7qAo=","_etag":"\"1\""}`, "")).
+		On(http.MethodPut, "/dbs/db1/colls/col1", testutil.Page(expected, ""))
+	client := testutil.NewStubClient(fake)
+	coll, _, warning, err := client.WithDatabase("db1").WithCollection("col1").SetIndexingModeLazy(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warning != interstellar.LazyIndexingWarning {
+		t.Errorf("expected warning %q, got %q", interstellar.LazyIndexingWarning, warning)
+	}
+	replaced := fake.RequestsTo(http.MethodPut, "/dbs/db1/colls/col1")
+	if len(replaced) != 1 || replaced[0].Body != expected {
+		t.Errorf("expected replace body:\n%s\ngot:\n%s", expected, testutil.ToJSON(replaced))
+	} else if ifMatch := replaced[0].Header.Get(interstellar.HeaderIfMatch); ifMatch != `"1"` {
+		t.Errorf("expected the replace to be conditional on the etag that was read, got If-Match %q", ifMatch)
+	}
+	if coll.IndexingPolicy == nil || coll.IndexingPolicy.IndexingMode == nil || *coll.IndexingPolicy.IndexingMode != interstellar.IndexingModeLazy {
+		t.Errorf("expected returned collection to have lazy indexing: %s", testutil.ToJSON(coll))
+	}
+}
+
+func TestCollectionReplaceIfMatch(t *testing.T) {
+	fake := testutil.NewFakeRequester().On(http.MethodPut, "/dbs/db1/colls/col1", testutil.Page(`{"id":"col1"}`, ""))
+	client := testutil.NewStubClient(fake)
+	cc := client.WithDatabase("db1").WithCollection("col1")
+	coll := &interstellar.CollectionResource{ID: "col1", ETag: `"1"`, IndexingPolicy: &interstellar.CollectionIndexingPolicy{}}
+	for _, opts := range []interstellar.RequestOptions{nil, &interstellar.CommonRequestOptions{IfMatch: `"2"`}} {
+		if _, _, err := cc.Replace(context.Background(), coll, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	coll.ETag = ""
+	if _, _, err := cc.Replace(context.Background(), coll, nil); err != nil {
+		t.Fatal(err)
+	}
+	var ifMatch []string
+	for _, req := range fake.Requests() {
+		ifMatch = append(ifMatch, req.Header.Get(interstellar.HeaderIfMatch))
+	}
+	if len(ifMatch) != 3 || ifMatch[0] != `"1"` || ifMatch[1] != `"2"` || ifMatch[2] != "" {
+		t.Errorf("unexpected If-Match headers: %q", ifMatch)
+	}
+
+	req, err := coll.ToCreateRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.IndexingPolicy.ExcludedPaths = append(req.IndexingPolicy.ExcludedPaths, &interstellar.CollectionExcludedPath{Path: "/x/*"})
+	if len(coll.IndexingPolicy.ExcludedPaths) != 0 {
+		t.Errorf("expected the request to have a copy of the indexing policy")
+	}
+}
+
+func TestCreateCollectionIfNotExists(t *testing.T) {
+	notFound := testutil.Status(http.StatusNotFound, `{"code":"NotFound"}`)
+	client := testutil.NewStubClient(testutil.NewFakeRequester().
+		On(http.MethodGet, "/dbs/db1/colls/col1", notFound, notFound, testutil.Page(`{"id":"col1"}`, "")).
+		On(http.MethodPost, "/dbs/db1/colls", testutil.Status(http.StatusCreated, `{"id":"col1"}`)))
+	db := client.WithDatabase("db1")
+	ctx := context.Background()
+	if exists, err := db.CollectionExists(ctx, "col1"); err != nil || exists {
+		t.Fatalf("expected collection not to exist: %v", err)
+	}
+	for i, expected := range []bool{true, false} {
+		coll, wasCreated, _, err := db.CreateCollectionIfNotExists(ctx, interstellar.CreateCollectionRequest{ID: "col1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wasCreated != expected || coll.ID != "col1" {
+			t.Errorf("call %d: expected created=%v, got created=%v for %s", i, expected, wasCreated, testutil.ToJSON(coll))
+		}
+	}
+	if exists, err := db.CollectionExists(ctx, "col1"); err != nil || !exists {
+		t.Fatalf("expected collection to exist: %v", err)
+	}
+}
+
+func TestCreateCollectionIfNotExistsConflict(t *testing.T) {
+	client := testutil.NewStubClient(testutil.NewFakeRequester().
+		On(http.MethodGet, "/dbs/db1/colls/col1", testutil.Status(http.StatusNotFound, `{"code":"NotFound"}`), testutil.Page(`{"id":"col1"}`, "")).
+		// created concurrently by someone else
+		On(http.MethodPost, "/dbs/db1/colls", testutil.Status(http.StatusConflict, `{"code":"Conflict"}`)))
+	coll, created, _, err := client.WithDatabase("db1").CreateCollectionIfNotExists(context.Background(), interstellar.CreateCollectionRequest{ID: "col1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created || coll.ID != "col1" {
+		t.Errorf("expected the existing collection, got created=%v for %s", created, testutil.ToJSON(coll))
+	}
+}
+
+func TestCollectionIndexTransformationProgress(t *testing.T) {
+	for _, progress := range []string{"", "0", "42", "100"} {
+		hdr := make(http.Header)
+		if progress != "" {
+			hdr.Set(interstellar.HeaderIndexTransformationProgress, progress)
+		}
+		client := testutil.NewStubClient(testutil.NewFakeRequester().
+			On(http.MethodGet, "/dbs/db1/colls/col1", testutil.FakeResponse{StatusCode: http.StatusOK, Header: hdr, Body: `{"id":"col1"}`}))
+		_, meta, err := client.WithDatabase("db1").WithCollection("col1").Get(context.Background(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if progress == "" {
+			if meta.IndexTransformationProgress != nil {
+				t.Errorf("expected unknown progress, got %d", *meta.IndexTransformationProgress)
+			}
+			continue
+		}
+		if meta.IndexTransformationProgress == nil || strconv.Itoa(*meta.IndexTransformationProgress) != progress {
+			t.Errorf("header '%s': expected progress %s, got %v", progress, progress, meta.IndexTransformationProgress)
+		}
+	}
+}
+
+func TestEnsureCollection(t *testing.T) {
+	fake := testutil.NewFakeRequester().
+		On(http.MethodGet, "/dbs/db1/colls/col1", testutil.Page(`{"id":"col1"}`, "")).
+		// created concurrently by someone else
+		On(http.MethodPost, "/dbs/db1/colls", testutil.Status(http.StatusConflict, `{"code":"Conflict"}`))
+	client := testutil.NewStubClient(fake)
+	coll, _, err := client.WithDatabase("db1").EnsureCollection(context.Background(), interstellar.CreateCollectionRequest{ID: "col1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gets := len(fake.RequestsTo(http.MethodGet, "/dbs/db1/colls/col1")); gets != 1 || coll.ID != "col1" {
+		t.Errorf("expected the existing collection after %d gets, got %s", gets, testutil.ToJSON(coll))
+	}
+}
+
+func TestCollectionInRegion(t *testing.T) {
+	var hosts []string
+	client := testutil.NewStubClient(testutil.RequesterFunc(func(req *http.Request) (*http.Response, error) {
+		hosts = append(hosts, req.URL.Host)
+		return testutil.NewResponse(req, http.StatusOK, nil, `{"id":"col1"}`), nil
+	}))
+	client.Endpoint = "https://account.documents.azure.com"
+	cc := client.WithDatabase("db1").WithCollection("col1")
+	west := cc.InRegion("https://account-westus.documents.azure.com")
+	for _, c := range []*interstellar.CollectionClient{cc, west} {
+		if _, _, err := c.Get(context.Background(), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(hosts) != 2 || hosts[0] != "account.documents.azure.com" || hosts[1] != "account-westus.documents.azure.com" {
+		t.Errorf("unexpected hosts: %v", hosts)
+	}
+	if client.Endpoint != "https://account.documents.azure.com" {
+		t.Errorf("expected the original client to be unchanged, got %s", client.Endpoint)
+	}
+}
+
+func TestCollectionInWriteRegion(t *testing.T) {
+	var hosts []string
+	client := testutil.NewStubClient(testutil.RequesterFunc(func(req *http.Request) (*http.Response, error) {
+		hosts = append(hosts, req.URL.Host)
+		return testutil.NewResponse(req, http.StatusCreated, nil, `{"id":"doc1"}`), nil
+	}))
+	client.Endpoint = "https://account.documents.azure.com"
+	account := &interstellar.AccountProperties{
+		ID: "account",
+		WritableLocations: []interstellar.AccountLocation{
+			{Name: "East US", DatabaseAccountEndpoint: "https://account-eastus.documents.azure.com"},
+			{Name: "West Europe", DatabaseAccountEndpoint: "https://account-westeurope.documents.azure.com"},
+		},
+		EnableMultipleWriteLocations: true,
+	}
+	cc := client.WithDatabase("db1").WithCollection("col1")
+	eu, err := cc.InWriteRegion(account, "west europe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = eu.CreateDocument(context.Background(), interstellar.CreateDocumentRequest{Document: map[string]string{"id": "doc1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 1 || hosts[0] != "account-westeurope.documents.azure.com" {
+		t.Errorf("unexpected hosts: %v", hosts)
+	}
+	if _, err = cc.InWriteRegion(account, "Australia East"); err == nil {
+		t.Error("expected an error for a region which is not writable")
+	}
+}
+
+func TestEstimateDocumentCount(t *testing.T) {
+	examples := []struct {
+		name  string
+		usage string
+		body  string
+		count int64
+		err   bool
+	}{
+		{name: "statistics", body: `{"id":"col1","partitionKeyRangeStatistics":[{"id":"0","documentCount":40},{"id":"1","documentCount":2}]}`, count: 42},
+		{name: "usage", usage: "documentsCount=7;collectionSize=3;", body: `{"id":"col1"}`, count: 7},
+		{name: "none", body: `{"id":"col1"}`, err: true},
+	}
+	for _, ex := range examples {
+		t.Run(ex.name, func(t *testing.T) {
+			hdr := make(http.Header)
+			if ex.usage != "" {
+				hdr.Set(interstellar.HeaderResourceUsage, ex.usage)
+			}
+			fake := testutil.NewFakeRequester().On(http.MethodGet, "/dbs/db1/colls/col1", testutil.FakeResponse{StatusCode: http.StatusOK, Header: hdr, Body: ex.body})
+			client := testutil.NewStubClient(fake)
+			count, err := client.WithDatabase("db1").WithCollection("col1").EstimateDocumentCount(context.Background())
+			if req := fake.Requests()[0]; req.Header.Get(interstellar.HeaderPopulatePartitionStatistics) != "true" || req.Header.Get(interstellar.HeaderDocDBPopulateQuotaInfo) != "true" {
+				t.Errorf("expected statistics and quota info to be requested")
+			}
+			if ex.err {
+				if err == nil {
+					t.Errorf("expected an error, got count %d", count)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != ex.count {
+				t.Errorf("expected %d, got %d", ex.count, count)
+			}
+		})
+	}
+}
@@ -16,6 +16,10 @@
 
 package interstellar
 
+import (
+	"encoding/json"
+)
+
 // CollectionResource represents a Collection container in Cosmos DB
 // Documentation adapted from adapted from docs.microsoft.com
 // See https://docs.microsoft.com/en-us/rest/api/cosmos-db/collections for the latest documentation
@@ -46,6 +50,25 @@ type CollectionResource struct {
 	IndexingPolicy *CollectionIndexingPolicy `json:"indexingPolicy,omitempty"`
 	// PartitionKey is the partitioning configuration settings for collection.
 	PartitionKey *CollectionPartitionKey `json:"partitionKey,omitempty"`
+	// VectorEmbeddingPolicy describes the vector embeddings stored in the collection's documents.
+	VectorEmbeddingPolicy *VectorEmbeddingPolicy `json:"vectorEmbeddingPolicy,omitempty"`
+	// GeospatialConfig selects the spatial type used for the collection's GeoJSON data.
+	GeospatialConfig *GeospatialConfig `json:"geospatialConfig,omitempty"`
+}
+
+// ToCreateRequest returns a request to create a collection with the same ID, indexing policy, partition key, vector embedding policy and geospatial config
+// This is useful for recreating a collection elsewhere, such as a copy of a live collection for testing or migration.
+// The throughput of the collection is an offer, and is not copied; set the OfferThroughput of the request if needed.
+// The policies are copied, so changing the request does not change the collection.
+func (c *CollectionResource) ToCreateRequest() (CreateCollectionRequest, error) {
+	// the request has the same JSON properties as the resource, so a round trip through JSON is a deep copy
+	var req CreateCollectionRequest
+	data, err := json.Marshal(c)
+	if err != nil {
+		return req, err
+	}
+	err = json.Unmarshal(data, &req)
+	return req, err
 }
 
 // CollectionIndexingPolicy represents the indexing policy configuration for a Collection
@@ -62,6 +85,9 @@ type CollectionIndexingPolicy struct {
 	IncludedPaths []*CollectionIncludedPath `json:"includedPaths,omitempty"`
 	// ExcludedPaths specifies Which paths must be excluded from indexing
 	ExcludedPaths []*CollectionExcludedPath `json:"excludedPaths,omitempty"`
+	// VectorIndexes specifies the paths of vector embeddings to index for vector search.
+	// Each path must also be described by the collection's VectorEmbeddingPolicy.
+	VectorIndexes []VectorIndexSpec `json:"vectorIndexes,omitempty"`
 }
 
 // CollectionExcludedPath represents a JSON Path to exclude from indexing inside a CollectionIndexingPolicy
@@ -75,6 +101,79 @@ type CollectionIncludedPath struct {
 	Indexes []*CollectionIndex `json:"indexes"`
 }
 
+// VectorIndexSpec represents a JSON Path to a vector embedding to index inside a CollectionIndexingPolicy
+type VectorIndexSpec struct {
+	Path string          `json:"path"`
+	Type VectorIndexType `json:"type"`
+}
+
+// VectorIndexType is the type of index used for vector search
+type VectorIndexType string
+
+const (
+	// VectorIndexTypeFlat stores vectors in full and performs an exact (brute force) search. Limited to 505 dimensions.
+	VectorIndexTypeFlat = VectorIndexType("flat")
+	// VectorIndexTypeQuantizedFlat stores compressed vectors and performs an exact search on the compressed vectors.
+	VectorIndexTypeQuantizedFlat = VectorIndexType("quantizedFlat")
+	// VectorIndexTypeDiskANN stores compressed vectors in a DiskANN graph, for fast approximate search of large numbers of vectors.
+	VectorIndexTypeDiskANN = VectorIndexType("diskANN")
+)
+
+// VectorEmbeddingPolicy describes the vector embeddings stored in the documents of a Collection
+type VectorEmbeddingPolicy struct {
+	VectorEmbeddings []VectorEmbedding `json:"vectorEmbeddings"`
+}
+
+// VectorEmbedding describes a vector embedding at a JSON Path in the documents of a Collection
+type VectorEmbedding struct {
+	// Path is the JSON Path of the vector embedding, such as "/embedding"
+	Path string `json:"path"`
+	// DataType is the type of the elements of the vector
+	DataType VectorDataType `json:"dataType"`
+	// DistanceFunction is the metric used to compute the similarity of two vectors
+	DistanceFunction VectorDistanceFunction `json:"distanceFunction"`
+	// Dimensions is the length of the vector
+	Dimensions int `json:"dimensions"`
+}
+
+// VectorDataType is the type of the elements of a vector embedding
+type VectorDataType string
+
+const (
+	// VectorDataTypeFloat32 denotes 32-bit floating point elements (the default)
+	VectorDataTypeFloat32 = VectorDataType("float32")
+	// VectorDataTypeUint8 denotes unsigned 8-bit integer elements
+	VectorDataTypeUint8 = VectorDataType("uint8")
+	// VectorDataTypeInt8 denotes signed 8-bit integer elements
+	VectorDataTypeInt8 = VectorDataType("int8")
+)
+
+// VectorDistanceFunction is the metric used to compute the similarity of two vector embeddings
+type VectorDistanceFunction string
+
+const (
+	// VectorDistanceEuclidean is the euclidean distance between two vectors
+	VectorDistanceEuclidean = VectorDistanceFunction("euclidean")
+	// VectorDistanceCosine is the cosine similarity of two vectors (the default)
+	VectorDistanceCosine = VectorDistanceFunction("cosine")
+	// VectorDistanceDotProduct is the dot product of two vectors
+	VectorDistanceDotProduct = VectorDistanceFunction("dotproduct")
+)
+
+// GeospatialConfig selects how a Collection interprets spatial (GeoJSON) data
+type GeospatialConfig struct {
+	// Type is either GeospatialTypeGeography (the default) or GeospatialTypeGeometry
+	Type string `json:"type"`
+}
+
+const (
+	// GeospatialTypeGeography represents data in a round-earth coordinate system (latitude and longitude)
+	GeospatialTypeGeography = "Geography"
+	// GeospatialTypeGeometry represents data in a flat (planar) coordinate system.
+	// Spatial indexes on a Geometry collection require a bounding box.
+	GeospatialTypeGeometry = "Geometry"
+)
+
 // CollectionIndex describes the type of data and precision that an included indexing path should used when being indexed.
 // From [Microsoft Documentation](https://docs.microsoft.com/en-us/rest/api/cosmos-db/collections#indexing-policy)
 // > The type or scheme used for index entries has a direct impact on index storage and performance.
@@ -99,6 +198,17 @@ const (
 	IndexingModeLazy = IndexingMode("Lazy")
 )
 
+// LazyIndexingWarning is the warning returned when a collection is switched to the Lazy indexing mode
+const LazyIndexingWarning = "interstellar: lazy indexing is eventually consistent; queries may not reflect recent writes (no read-your-writes guarantee)"
+
+// SetIndexingModeLazy sets the IndexingMode of the policy to Lazy and returns the LazyIndexingWarning.
+// Lazy indexing updates the index asynchronously, so query results may lag behind writes to the collection.
+func (p *CollectionIndexingPolicy) SetIndexingModeLazy() string {
+	mode := IndexingModeLazy
+	p.IndexingMode = &mode
+	return LazyIndexingWarning
+}
+
 // DataType is the data type used for indexing.
 type DataType string
 
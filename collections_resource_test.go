@@ -59,3 +59,35 @@ func TestCollectionResourceMarshallJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestCollectionResourceToCreateRequest(t *testing.T) {
+	testdata := testutil.ReadFileBytes(t, filepath.Join("./testdata", "collections.json"))
+	var colls []interstellar.CollectionResource
+	if err := json.Unmarshal(testdata, &colls); err != nil {
+		t.Fatal(err)
+	}
+	for _, coll := range colls {
+		t.Run(coll.ID, func(t *testing.T) {
+			req, err := coll.ToCreateRequest()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal(&req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var created interstellar.CollectionResource
+			if err := json.Unmarshal(data, &created); err != nil {
+				t.Fatal(err)
+			}
+			if created.ResourceID != "" || created.ETag != "" {
+				t.Errorf("expected system properties to be omitted: %s", data)
+			}
+			created.ResourceID, created.Timestamp, created.Self, created.ETag = coll.ResourceID, coll.Timestamp, coll.Self, coll.ETag
+			created.Docs, created.Sprocs, created.Triggers, created.UDFs, created.Conflicts = coll.Docs, coll.Sprocs, coll.Triggers, coll.UDFs, coll.Conflicts
+			if diff := deep.Equal(&coll, &created); diff != nil {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
@@ -21,7 +21,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/url"
 )
 
 // DatabaseResource represents a Database in Cosmos DB
@@ -72,6 +71,49 @@ func (c *Client) CreateDatabase(ctx context.Context, id string, opts RequestOpti
 	return &db, meta, err
 }
 
+// DatabaseExists checks if the database with the given ID exists
+func (c *Client) DatabaseExists(ctx context.Context, id string) (bool, error) {
+	_, _, err := c.WithDatabase(id).GetRaw(ctx, nil)
+	switch err {
+	case nil:
+		return true, nil
+	case ErrResourceNotFound:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// CreateDatabaseIfNotExists gets the database with the given ID, or creates it if it does not exist
+// Returns true if the database was created.
+// If the database is created concurrently by someone else, the conflict is handled by getting the database again.
+func (c *Client) CreateDatabaseIfNotExists(ctx context.Context, id string, opts RequestOptions) (*DatabaseResource, bool, *ResponseMetadata, error) {
+	db, meta, err := c.WithDatabase(id).Get(ctx, nil)
+	if err != ErrResourceNotFound {
+		return db, false, meta, err
+	}
+	db, meta, err = c.CreateDatabase(ctx, id, opts)
+	if err == ErrResourceConflict {
+		db, meta, err = c.WithDatabase(id).Get(ctx, nil)
+		return db, false, meta, err
+	}
+	if err != nil {
+		return nil, false, meta, err
+	}
+	return db, true, meta, nil
+}
+
+// EnsureDatabase creates the database with the given ID, or gets it if it already exists
+// Unlike CreateDatabaseIfNotExists, the create is attempted first; a conflict with a concurrent creator is handled by getting the existing database.
+// This is intended for bootstrapping services with many instances, where usually only the first create succeeds.
+func (c *Client) EnsureDatabase(ctx context.Context, id string) (*DatabaseResource, *ResponseMetadata, error) {
+	db, meta, err := c.CreateDatabase(ctx, id, nil)
+	if err == ErrResourceConflict {
+		return c.WithDatabase(id).Get(ctx, nil)
+	}
+	return db, meta, err
+}
+
 // ListDatabasesRaw lists each database in the CosmosDB Account as raw JSON objects given to the pagination function
 func (c *Client) ListDatabasesRaw(ctx context.Context, opts RequestOptions, fn PaginateRawResources) error {
 	return c.ListResources(ctx, "Databases", ClientRequest{
@@ -117,7 +159,7 @@ func (c *Client) WithDatabase(id string) *DatabaseClient {
 
 // ResourceLink gets the resource link for the database
 func (c *DatabaseClient) ResourceLink() string {
-	return fmt.Sprintf("dbs/%s", url.PathEscape(c.DatabaseID))
+	return BuildResourceLink("dbs", c.DatabaseID)
 }
 
 // GetRaw retrieves the raw database resource
@@ -155,3 +197,21 @@ func (c *DatabaseClient) Delete(ctx context.Context, opts RequestOptions) (bool,
 		Options:      opts,
 	})
 }
+
+// DeleteIfExists will delete the database, and treats a database which does not exist as already deleted
+// Returns false (and no error) if the database did not exist.
+func (c *DatabaseClient) DeleteIfExists(ctx context.Context, opts RequestOptions) (bool, *ResponseMetadata, error) {
+	ok, meta, err := c.Delete(ctx, opts)
+	if err == ErrResourceNotFound {
+		return false, meta, nil
+	}
+	return ok, meta, err
+}
+
+// WithOptions creates a copy of the DatabaseClient which applies the options to every request made by it
+// See Client.WithOptions
+func (c *DatabaseClient) WithOptions(opts RequestOptions) *DatabaseClient {
+	cc := *c
+	cc.Client = c.Client.WithOptions(opts)
+	return &cc
+}
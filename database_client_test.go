@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package interstellar_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/jet/go-interstellar/internal/testutil"
+)
+
+func TestCreateDatabaseIfNotExists(t *testing.T) {
+	notFound := testutil.Status(http.StatusNotFound, `{"code":"NotFound"}`)
+	client := testutil.NewStubClient(testutil.NewFakeRequester().
+		On(http.MethodGet, "/dbs/db1", notFound, notFound, testutil.Page(`{"id":"db1"}`, "")).
+		On(http.MethodPost, "/dbs", testutil.Status(http.StatusCreated, `{"id":"db1"}`)))
+	ctx := context.Background()
+	if exists, err := client.DatabaseExists(ctx, "db1"); err != nil || exists {
+		t.Fatalf("expected database not to exist: %v", err)
+	}
+	for i, expected := range []bool{true, false} {
+		db, wasCreated, _, err := client.CreateDatabaseIfNotExists(ctx, "db1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wasCreated != expected || db.ID != "db1" {
+			t.Errorf("call %d: expected created=%v, got created=%v for %s", i, expected, wasCreated, testutil.ToJSON(db))
+		}
+	}
+	if exists, err := client.DatabaseExists(ctx, "db1"); err != nil || !exists {
+		t.Fatalf("expected database to exist: %v", err)
+	}
+}
+
+func TestEnsureDatabase(t *testing.T) {
+	client := testutil.NewStubClient(testutil.NewFakeRequester().
+		On(http.MethodGet, "/dbs/db1", testutil.Page(`{"id":"db1"}`, "")).
+		On(http.MethodPost, "/dbs", testutil.Status(http.StatusCreated, `{"id":"db1"}`), testutil.Status(http.StatusConflict, `{"code":"Conflict"}`)))
+	for i := 0; i < 2; i++ {
+		db, _, err := client.EnsureDatabase(context.Background(), "db1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if db.ID != "db1" {
+			t.Errorf("call %d: unexpected database %s", i, testutil.ToJSON(db))
+		}
+	}
+}
+
+func TestDeleteDatabaseIfExists(t *testing.T) {
+	client := testutil.NewStubClient(testutil.NewFakeRequester().On(http.MethodDelete, "/dbs/db1",
+		testutil.Status(http.StatusNoContent, ""),
+		testutil.Status(http.StatusNotFound, ""),
+		testutil.Status(http.StatusInternalServerError, "")))
+	db := client.WithDatabase("db1")
+	ok, _, err := db.DeleteIfExists(context.Background(), nil)
+	if err != nil || !ok {
+		t.Errorf("expected the database to be deleted: %v", err)
+	}
+	ok, _, err = db.DeleteIfExists(context.Background(), nil)
+	if err != nil || ok {
+		t.Errorf("expected a missing database to be ignored: %v", err)
+	}
+	if _, _, err = db.DeleteIfExists(context.Background(), nil); err == nil {
+		t.Error("expected other errors to be returned")
+	}
+}
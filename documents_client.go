@@ -22,8 +22,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"net/url"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
 )
 
 // HeaderIndexingDirective is used to enable or disable indexing on the resource.
@@ -32,6 +36,16 @@ const HeaderIndexingDirective = "x-ms-indexing-directive"
 // HeaderDocDBIsUpsert is set to true if the document should be created if it does not exist, or updated in-place if it does.
 const HeaderDocDBIsUpsert = "x-ms-documentdb-is-upsert"
 
+// HeaderAllowTentativeWrites is set to true to allow writes to be accepted tentatively by a region of a multi-master account, such as during a region failover.
+// Conflicting tentative writes are resolved by the collection's conflict resolution policy.
+const HeaderAllowTentativeWrites = "x-ms-cosmos-allow-tentative-writes"
+
+// HeaderPrefer is set to PreferReturnMinimal to omit the resource from the response body of a write
+const HeaderPrefer = "Prefer"
+
+// PreferReturnMinimal is the value of the Prefer header which omits the resource from the response body of a write
+const PreferReturnMinimal = "return=minimal"
+
 // DocumentClient is a client scoped to a single document
 // Used to perform API calls within the scope of a single Document resource
 type DocumentClient struct {
@@ -40,36 +54,67 @@ type DocumentClient struct {
 	CollectionID string
 	DocumentID   string
 	PartitionKey []string
+
+	// IndexingDirective is the default indexing directive when replacing the document
+	// It is overridden by ReplaceDocumentRequest.IndexingDirective
+	IndexingDirective *DocumentIndexingDirective
+
+	// ConsistencyLevel is the default consistency level when reading the document
+	// It is overridden by the consistency level of the options of each read.
+	ConsistencyLevel ConsistencyLevel
+
+	// Partitioned indicates the document is in a partitioned collection
+	// If set, replacing the document without a partition key returns ErrPartitionKeyRequired instead of sending the request.
+	Partitioned bool
+
+	// StripSystemProperties removes the SystemProperties from the document when it is read
+	// The ETag of the document is still returned in the ResponseMetadata. See CollectionClient.StripSystemProperties
+	StripSystemProperties bool
 }
 
 // WithDocument creates a DocumentClient for the given Document ID and PartitionKey within this Collection
 func (c *CollectionClient) WithDocument(id string, partitionKey []string) *DocumentClient {
 	return &DocumentClient{
-		Client:       c.Client,
-		DatabaseID:   c.DatabaseID,
-		CollectionID: c.CollectionID,
-		DocumentID:   id,
-		PartitionKey: partitionKey,
+		Client:                c.Client,
+		DatabaseID:            c.DatabaseID,
+		CollectionID:          c.CollectionID,
+		DocumentID:            id,
+		PartitionKey:          partitionKey,
+		IndexingDirective:     c.IndexingDirective,
+		ConsistencyLevel:      c.ConsistencyLevel,
+		Partitioned:           c.Partitioned,
+		StripSystemProperties: c.StripSystemProperties,
 	}
 }
 
+// Document creates a DocumentClient for the given Document ID and PartitionKey within the given Database and Collection
+// This is a shortcut for client.WithDatabase(databaseID).WithCollection(collectionID).WithDocument(id, partitionKey)
+func (c *Client) Document(databaseID, collectionID, id string, partitionKey []string) *DocumentClient {
+	return c.Collection(databaseID, collectionID).WithDocument(id, partitionKey)
+}
+
 // ResourceLink gets the resource link for the document
 func (c *DocumentClient) ResourceLink() string {
-	return fmt.Sprintf("dbs/%s/colls/%s/docs/%s", url.PathEscape(c.DatabaseID), url.PathEscape(c.CollectionID), url.PathEscape(c.DocumentID))
+	return BuildResourceLink("dbs", c.DatabaseID, "colls", c.CollectionID, "docs", c.DocumentID)
 }
 
 func (c *DocumentClient) addPartitionKey(opts RequestOptions) RequestOptions {
-	if len(c.PartitionKey) == 0 {
-		return opts
+	return addPartitionKey(opts, c.PartitionKey)
+}
+
+// DefaultMaxDocumentBytes is the maximum size of a document accepted by Cosmos DB (2 MB)
+const DefaultMaxDocumentBytes = 2 * 1024 * 1024
+
+// checkDocumentSize returns ErrPayloadTooLarge (the same as a write rejected by the server) if the document is larger than max bytes
+// If max is zero, DefaultMaxDocumentBytes is used; if negative, the size is not checked.
+func checkDocumentSize(body []byte, max int) error {
+	if max == 0 {
+		max = DefaultMaxDocumentBytes
 	}
-	fn := RequestOptionsFunc(func(req *http.Request) {
-		b, _ := json.Marshal(c.PartitionKey)
-		req.Header.Set(HeaderDocDBPartitionKey, string(b))
-	})
-	if opts == nil {
-		return fn
+	if max > 0 && len(body) > max {
+		return ErrPayloadTooLarge
 	}
-	return RequestOptionsList{opts, fn}
+	return nil
 }
 
 // CreateDocumentRequest are parameters for CreateDocument
@@ -95,16 +140,39 @@ type CreateDocumentRequest struct {
 
 	// Unmarshaler is an optional Unmarshaler that will be called with the response body
 	Unmarshaler json.Unmarshaler
+
+	// Validator is an optional function that will be called with the marshalled document before it is sent.
+	// If it returns an error, the document is not created and the error is returned. See RequiredFields for a simple validator.
+	Validator DocumentValidator
+
+	// AllowTentativeWrites allows the write to be accepted tentatively on multi-master accounts. See HeaderAllowTentativeWrites
+	AllowTentativeWrites bool
+
+	// ReturnMinimal asks for the created document to be omitted from the response, to save bandwidth when it is not needed
+	// The response body is then empty (and the Unmarshaler is not called), but the ResponseMetadata (including the ETag) is still returned.
+	ReturnMinimal bool
+
+	// MaxDocumentBytes is the size limit of the marshalled document, checked before it is sent, so a larger document fails with ErrPayloadTooLarge
+	// If zero, DefaultMaxDocumentBytes is used; if negative, the document is sent regardless of its size.
+	MaxDocumentBytes int
 }
 
 func (r CreateDocumentRequest) json() ([]byte, error) {
 	if r.Body == nil && r.Document == nil {
 		return nil, Error("interstellar: must set either a Document or a Body for CreateDocumentRequest")
 	}
-	if len(r.Body) == 0 {
-		return json.Marshal(r.Document)
+	body := r.Body
+	if len(body) == 0 {
+		b, err := json.Marshal(r.Document)
+		if err != nil {
+			return nil, err
+		}
+		body = b
 	}
-	return r.Body, nil
+	if err := checkDocumentSize(body, r.MaxDocumentBytes); err != nil {
+		return nil, err
+	}
+	return body, nil
 }
 
 // ApplyOptions applies the request options to the api request
@@ -119,29 +187,103 @@ func (r CreateDocumentRequest) ApplyOptions(req *http.Request) {
 	if r.IndexingDirective != nil {
 		req.Header.Set(HeaderIndexingDirective, string(*r.IndexingDirective))
 	}
+	if r.AllowTentativeWrites {
+		req.Header.Set(HeaderAllowTentativeWrites, "true")
+	}
+	if r.ReturnMinimal {
+		req.Header.Set(HeaderPrefer, PreferReturnMinimal)
+	}
 	if r.Options != nil {
 		r.Options.ApplyOptions(req)
 	}
 }
 
-// CreateDocument creates or updates a document in the collection
-func (c *CollectionClient) CreateDocument(ctx context.Context, req CreateDocumentRequest) ([]byte, *ResponseMetadata, error) {
+// Validate checks that the request is well-formed without sending it.
+// The document must marshal into a JSON object, and pass the Validator (if set).
+//
+// The partition key is checked against pk, which is the partition key definition of the collection (see CollectionResource).
+// If pk is nil, the collection is not partitioned and no PartitionKey may be set.
+// Otherwise, a PartitionKey must be set, and it must match the value at each of the partition key paths in the document.
+func (r CreateDocumentRequest) Validate(pk *CollectionPartitionKey) error {
+	body, err := r.json()
+	if err != nil {
+		return errors.Wrap(err, "interstellar: document could not be marshalled")
+	}
+	doc, err := decodeDocument(body)
+	if err != nil {
+		return errors.Wrap(err, "interstellar: document is not valid JSON")
+	}
+	if _, ok := doc.(map[string]interface{}); !ok {
+		return Error("interstellar: document must be a JSON object")
+	}
+	if r.Validator != nil {
+		if err = r.Validator(body); err != nil {
+			return errors.Wrap(err, "interstellar: document failed validation")
+		}
+	}
+	if pk == nil || len(pk.Paths) == 0 {
+		if len(r.PartitionKey) > 0 {
+			return Error("interstellar: partition key must not be set for a non-partitioned collection")
+		}
+		return nil
+	}
+	if len(r.PartitionKey) != len(pk.Paths) {
+		return errors.Errorf("interstellar: partition key must have %d value(s) for paths %v", len(pk.Paths), pk.Paths)
+	}
+	for i, path := range pk.Paths {
+		v, ok := documentValueAt(doc, path)
+		if !ok || partitionKeyValue(v) != r.PartitionKey[i] {
+			return errors.Errorf("interstellar: partition key '%s' does not match the document value at '%s'", r.PartitionKey[i], path)
+		}
+	}
+	return nil
+}
+
+func (c *CollectionClient) createDocumentRequest(req CreateDocumentRequest) (ClientRequest, error) {
+	if req.IndexingDirective == nil {
+		req.IndexingDirective = c.IndexingDirective
+	}
 	body, err := req.json()
 	if err != nil {
-		return nil, nil, err
+		return ClientRequest{}, err
+	}
+	if req.Validator != nil {
+		if err = req.Validator(body); err != nil {
+			return ClientRequest{}, errors.Wrap(err, "interstellar: document failed validation")
+		}
 	}
 	rl := c.ResourceLink()
-	data, meta, err := c.Client.CreateOrReplaceResource(ctx, ClientRequest{
+	return ClientRequest{
+		Method:       http.MethodPost,
 		Path:         fmt.Sprintf("/%s/docs", rl),
 		ResourceLink: rl,
 		ResourceType: ResourceDocuments,
 		Body:         bytes.NewBuffer(body),
 		Options:      req,
-	})
+	}, nil
+}
+
+// BuildCreateDocument builds the http request that CreateDocument would send, without sending it.
+// This can be used as a dry run to check that documents marshal and produce well-formed requests.
+func (c *CollectionClient) BuildCreateDocument(ctx context.Context, req CreateDocumentRequest) (*http.Request, error) {
+	creq, err := c.createDocumentRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return c.Client.BuildRequest(ctx, creq)
+}
+
+// CreateDocument creates or updates a document in the collection
+func (c *CollectionClient) CreateDocument(ctx context.Context, req CreateDocumentRequest) ([]byte, *ResponseMetadata, error) {
+	creq, err := c.createDocumentRequest(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, meta, err := c.Client.CreateOrReplaceResource(ctx, creq)
 	if err != nil {
 		return nil, meta, err
 	}
-	if req.Unmarshaler != nil {
+	if req.Unmarshaler != nil && len(data) > 0 {
 		if err = req.Unmarshaler.UnmarshalJSON(data); err != nil {
 			return nil, meta, err
 		}
@@ -149,6 +291,37 @@ func (c *CollectionClient) CreateDocument(ctx context.Context, req CreateDocumen
 	return data, meta, nil
 }
 
+// PartitionKeyDeleteAPIVersion is the minimum REST API version which supports deleting all documents by partition key
+// Requests made by DeleteAllDocumentsByPartitionKey are sent with this version, instead of APIVersion.
+const PartitionKeyDeleteAPIVersion = "2020-07-15"
+
+// ResourcePartitionKey is the resource type used when deleting all documents by partition key
+const ResourcePartitionKey ResourceType = "partitionkey"
+
+// DeleteAllDocumentsByPartitionKey deletes every document in the logical partition with the given partition key.
+// This is much cheaper than querying and deleting each document, such as when removing all data for a tenant.
+// The deletion happens asynchronously in the background; documents may still be returned for a short time after this call returns.
+//
+// Note: This is a preview feature of CosmosDB, and must be enabled on the account; otherwise the request fails with an error.
+func (c *CollectionClient) DeleteAllDocumentsByPartitionKey(ctx context.Context, partitionKey []string, opts RequestOptions) (*ResponseMetadata, error) {
+	if len(partitionKey) == 0 {
+		return nil, errors.New("interstellar: a partition key is required to delete all documents by partition key")
+	}
+	version := RequestOptionsFunc(func(req *http.Request) {
+		req.Header.Set(HeaderMSAPIVersion, PartitionKeyDeleteAPIVersion)
+	})
+	opts = mergeOptions(opts, version)
+	rl := c.ResourceLink()
+	_, meta, err := c.Client.CreateOrReplaceResource(ctx, ClientRequest{
+		Method:       http.MethodPost,
+		Path:         fmt.Sprintf("/%s/operations/partitionkeydelete", rl),
+		ResourceLink: rl,
+		ResourceType: ResourcePartitionKey,
+		Options:      addPartitionKey(opts, partitionKey),
+	})
+	return meta, err
+}
+
 // ListDocumentsRaw lists each document in the collection as raw JSON objects
 func (c *CollectionClient) ListDocumentsRaw(ctx context.Context, opts RequestOptions, fn PaginateRawResources) error {
 	rl := c.ResourceLink()
@@ -156,16 +329,211 @@ func (c *CollectionClient) ListDocumentsRaw(ctx context.Context, opts RequestOpt
 		Path:         fmt.Sprintf("/%s/docs", rl),
 		ResourceLink: rl,
 		ResourceType: ResourceDocuments,
-		Options:      opts,
-	}, fn)
+		Options:      withConsistency(c.ConsistencyLevel, opts),
+	}, stripSystemPropertiesFrom(c.StripSystemProperties, fn))
+}
+
+// StreamDocumentsRaw lists each document in the collection in a background goroutine, sending each raw document on the returned channel
+// The document channel is closed when the listing ends; then the error channel receives the error (if any) and is closed.
+//
+//     docs, errc := cc.StreamDocumentsRaw(ctx, nil)
+//     for doc := range docs {
+//         // process doc
+//     }
+//     if err := <-errc; err != nil {
+//         return err
+//     }
+//
+// The caller must either drain the document channel or cancel the context, otherwise the goroutine is leaked.
+// When the context is cancelled, the listing stops and the context's error is sent on the error channel.
+func (c *CollectionClient) StreamDocumentsRaw(ctx context.Context, opts RequestOptions) (<-chan json.RawMessage, <-chan error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	docs := make(chan json.RawMessage)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		defer close(docs)
+		err := c.ListDocumentsRaw(ctx, opts, func(resList []json.RawMessage, meta ResponseMetadata) (bool, error) {
+			for _, doc := range resList {
+				if err := ctx.Err(); err != nil {
+					return false, err
+				}
+				select {
+				case docs <- doc:
+				case <-ctx.Done():
+					return false, ctx.Err()
+				}
+			}
+			return true, nil
+		})
+		if err != nil {
+			errc <- err
+		}
+	}()
+	return docs, errc
 }
 
 // QueryDocumentsRaw posts the query to the collection and paginates through the results using the supplied paginate function
 func (c *CollectionClient) QueryDocumentsRaw(ctx context.Context, query *Query, fn PaginateRawResources) error {
+	return c.queryDocumentsRaw(ctx, query, nil, fn)
+}
+
+// ReadDocumentsByIDs reads the documents with the given IDs from a single logical partition, with one query instead of a point read per document
+// The query is scoped to the partition key, so this is much cheaper than separate reads when many IDs share a partition.
+// For a non-partitioned collection, the partition key is empty. Documents which do not exist are left out of the results,
+// and the results are in the order they are returned by the query (not the order of the IDs).
+func (c *CollectionClient) ReadDocumentsByIDs(ctx context.Context, partitionKey []string, ids []string) ([]json.RawMessage, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	query := &Query{Query: "SELECT * FROM c WHERE ARRAY_CONTAINS(@ids, c.id)"}
+	query.AddParameter("@ids", ids)
+	var docs []json.RawMessage
+	err := c.queryDocumentsRaw(ctx, query, addPartitionKey(nil, partitionKey), func(resList []json.RawMessage, meta ResponseMetadata) (bool, error) {
+		docs = append(docs, resList...)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// ErrMultipleResults is returned by QueryOneDocument when the query returns more than one document
+const ErrMultipleResults = Error("interstellar: query returned more than one result")
+
+// QueryOneDocument runs a query which is expected to return at most one document, such as a lookup by a unique natural key,
+// and unmarshals the document into v. If the query returns no documents, found is false and v is left unchanged.
+// If the query returns more than one document, ErrMultipleResults is returned; use QueryFirstDocument to accept the first of them.
+// The metadata is of the last page read.
+func (c *CollectionClient) QueryOneDocument(ctx context.Context, query *Query, v interface{}) (bool, *ResponseMetadata, error) {
+	return c.queryOneDocument(ctx, query, v, true)
+}
+
+// QueryFirstDocument runs a query and unmarshals the first document it returns into v, ignoring any others
+// If the query returns no documents, found is false and v is left unchanged.
+// Use an ORDER BY (or TOP 1) in the query when the choice of document matters.
+func (c *CollectionClient) QueryFirstDocument(ctx context.Context, query *Query, v interface{}) (bool, *ResponseMetadata, error) {
+	return c.queryOneDocument(ctx, query, v, false)
+}
+
+func (c *CollectionClient) queryOneDocument(ctx context.Context, query *Query, v interface{}, strict bool) (bool, *ResponseMetadata, error) {
+	var first json.RawMessage
+	var last *ResponseMetadata
+	err := c.queryDocumentsRaw(ctx, query, nil, func(resList []json.RawMessage, meta ResponseMetadata) (bool, error) {
+		last = &meta
+		for _, doc := range resList {
+			if first != nil {
+				return false, ErrMultipleResults
+			}
+			first = doc
+			if !strict {
+				return false, nil
+			}
+		}
+		// pages of a cross-partition query may be empty, so keep reading until there is a second result or no more pages
+		return true, nil
+	})
+	if err != nil {
+		return false, last, err
+	}
+	if first == nil {
+		return false, last, nil
+	}
+	if err = json.Unmarshal(first, v); err != nil {
+		return false, last, err
+	}
+	return true, last, nil
+}
+
+// NewProjectionQuery creates a query which selects only the given fields of each document, such as SELECT c["id"], c["name"] FROM c
+// Each field is a property path with the property names separated by dots, such as "address.city".
+// The property names are quoted, so they may be reserved words (such as "value") or contain any characters other than dots.
+// A field with a single property is returned under its own name. A nested field is returned under an alias of its property names
+// joined by underscores (with any characters which are not valid in an identifier replaced by underscores), such as "address_city",
+// so fields with the same last property (such as "billing.city" and "shipping.city") do not collide.
+// An error is returned if two fields would be returned under the same name.
+// Selecting fewer fields than SELECT * reduces the size of the results, and the RU charged for them.
+func NewProjectionQuery(projection []string) (*Query, error) {
+	if len(projection) == 0 {
+		return nil, Error("interstellar: projection must have at least one field")
+	}
+	fields := make([]string, len(projection))
+	names := make(map[string]string, len(projection))
+	for i, field := range projection {
+		parts := strings.Split(field, ".")
+		var expr strings.Builder
+		expr.WriteString("c")
+		for _, part := range parts {
+			if part == "" {
+				return nil, errors.Errorf("interstellar: invalid projection field '%s'", field)
+			}
+			quoted, err := json.Marshal(part)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&expr, "[%s]", quoted)
+		}
+		name := parts[0]
+		if len(parts) > 1 {
+			name = projectionAlias(parts)
+			fmt.Fprintf(&expr, " AS %s", name)
+		}
+		if other, ok := names[name]; ok {
+			return nil, errors.Errorf("interstellar: projection fields '%s' and '%s' are both returned as '%s'", other, field, name)
+		}
+		names[name] = field
+		fields[i] = expr.String()
+	}
+	return &Query{
+		Query:                fmt.Sprintf("SELECT %s FROM c", strings.Join(fields, ", ")),
+		EnableCrossPartition: true,
+	}, nil
+}
+
+// projectionAlias joins the property names of a nested field into an identifier, such as address_city
+// The alias always contains an underscore, so it is never a reserved word.
+func projectionAlias(parts []string) string {
+	alias := []rune(strings.Join(parts, "_"))
+	for i, r := range alias {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			alias[i] = '_'
+		}
+	}
+	if alias[0] >= '0' && alias[0] <= '9' {
+		return "_" + string(alias)
+	}
+	return string(alias)
+}
+
+// ListDocumentsProjected lists each document in the collection with only the fields of the projection (see NewProjectionQuery)
+// This is much cheaper than ListDocumentsRaw for large documents when only a few fields (such as the id) are needed.
+func (c *CollectionClient) ListDocumentsProjected(ctx context.Context, projection []string, fn PaginateRawResources) error {
+	query, err := NewProjectionQuery(projection)
+	if err != nil {
+		return err
+	}
+	return c.QueryDocumentsRaw(ctx, query, fn)
+}
+
+// QueryDocumentsInRange posts the query to a single partition key range of the collection and paginates through the results using the supplied paginate function
+// The rangeID is the ID of a PartitionKeyRangeResource (see ListPartitionKeyRanges).
+// This is the building block for custom parallel (fan-out) query execution, where each partition key range is queried independently.
+// The continuation tokens returned in the ResponseMetadata are scoped to the partition key range.
+func (c *CollectionClient) QueryDocumentsInRange(ctx context.Context, rangeID string, query *Query, fn PaginateRawResources) error {
+	return c.queryDocumentsRaw(ctx, query, RequestOptionsFunc(func(req *http.Request) {
+		req.Header.Set(HeaderDocDBPartitionKeyRangeID, rangeID)
+	}), fn)
+}
+
+func (c *CollectionClient) queryDocumentsRaw(ctx context.Context, query *Query, opts RequestOptions, fn PaginateRawResources) error {
 	if query == nil {
 		return Error("interstellar: query cannot be nil")
 	}
-	rl := fmt.Sprintf("dbs/%s/colls/%s", url.PathEscape(c.DatabaseID), url.PathEscape(c.CollectionID))
+	qopts := withConsistency(c.ConsistencyLevel, mergeOptions(query, opts))
+	rl := c.ResourceLink()
 	qjson, err := json.Marshal(&query)
 	if err != nil {
 		return err
@@ -175,20 +543,141 @@ func (c *CollectionClient) QueryDocumentsRaw(ctx context.Context, query *Query,
 		Path:         fmt.Sprintf("/%s/docs", rl),
 		ResourceLink: rl,
 		ResourceType: ResourceDocuments,
-		Options:      query,
+		Options:      qopts,
 		Body:         bytes.NewBuffer(qjson),
-	}, fn)
+	}, stripSystemPropertiesFrom(c.StripSystemProperties && selectAllPattern.MatchString(query.Query), fn))
+}
+
+// selectAllPattern matches a query which selects whole documents, such as SELECT * FROM c
+// The system properties are only stripped from the results of these queries, since a projection selects the properties it wants.
+var selectAllPattern = regexp.MustCompile(`(?is)^\s*SELECT\s+(DISTINCT\s+)?(TOP\s+\d+\s+)?\*`)
+
+// QueryPageBase64 runs a single page of the query and returns the results, along with an opaque cursor for the next page.
+// This is meant for stateless web APIs which hand the cursor back to their callers, such as in a URL query parameter.
+//
+// The cursor is the URL-safe base64 encoding of the continuation token (see EncodeQueryCursor).
+// It is empty when there are no more pages.
+// To get the next page, decode the cursor with DecodeQueryCursor and set it as the query Continuation:
+//
+//     query.Continuation, err = interstellar.DecodeQueryCursor(cursor)
+//     results, cursor, err = cc.QueryPageBase64(ctx, query)
+//
+func (c *CollectionClient) QueryPageBase64(ctx context.Context, query *Query) ([]json.RawMessage, string, error) {
+	var results []json.RawMessage
+	var cursor string
+	err := c.QueryDocumentsRaw(ctx, query, func(resList []json.RawMessage, meta ResponseMetadata) (bool, error) {
+		results = resList
+		cursor = EncodeQueryCursor(meta.Continuation)
+		return false, nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return results, cursor, nil
+}
+
+// aggregatePattern matches the single-value aggregate function of a query, such as SELECT VALUE COUNT(1) FROM c
+var aggregatePattern = regexp.MustCompile(`(?i)^\s*SELECT\s+VALUE\s+(COUNT|SUM|AVG)\s*\(`)
+
+// partialAggregate is a partial aggregate result for a page or partition
+type partialAggregate struct {
+	value    float64
+	sum      float64
+	count    float64
+	hasCount bool
+}
+
+func parsePartialAggregate(raw json.RawMessage) (partialAggregate, error) {
+	var value float64
+	if err := json.Unmarshal(raw, &value); err == nil {
+		return partialAggregate{value: value}, nil
+	}
+	var obj struct {
+		Item  *json.RawMessage `json:"item"`
+		Sum   *float64         `json:"sum"`
+		Count *float64         `json:"count"`
+	}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return partialAggregate{}, errors.Wrapf(err, "interstellar: unrecognized aggregate result %s", raw)
+	}
+	switch {
+	case obj.Item != nil:
+		return parsePartialAggregate(*obj.Item)
+	case obj.Sum != nil && obj.Count != nil:
+		return partialAggregate{sum: *obj.Sum, count: *obj.Count, hasCount: true}, nil
+	}
+	return partialAggregate{}, errors.Errorf("interstellar: unrecognized aggregate result %s", raw)
+}
+
+// QueryAggregate runs a single-value COUNT, SUM or AVG aggregate query (such as SELECT VALUE COUNT(1) FROM c) and combines the partial results
+// Cross-partition aggregate queries return a partial aggregate for each partition (across one or more pages),
+// which are summed for COUNT and SUM. An AVG is weighted by the count of each partial result,
+// so it requires partial results of the form {"sum": ..., "count": ...}; averaging the averages would be wrong.
+// The exception is a single-partition AVG, whose one result is the average itself.
+// A query with no results returns zero.
+func (c *CollectionClient) QueryAggregate(ctx context.Context, query *Query) (float64, error) {
+	if query == nil {
+		return 0, Error("interstellar: query cannot be nil")
+	}
+	m := aggregatePattern.FindStringSubmatch(query.Query)
+	if m == nil {
+		return 0, Error("interstellar: query is not a single-value COUNT, SUM or AVG aggregate")
+	}
+	fn := strings.ToUpper(m[1])
+	var total, sum, count float64
+	var averages []partialAggregate
+	err := c.QueryDocumentsRaw(ctx, query, func(resList []json.RawMessage, meta ResponseMetadata) (bool, error) {
+		for _, raw := range resList {
+			partial, err := parsePartialAggregate(raw)
+			if err != nil {
+				return false, err
+			}
+			if fn == "AVG" {
+				averages = append(averages, partial)
+				continue
+			}
+			if partial.hasCount {
+				return false, errors.Errorf("interstellar: unexpected partial average for %s", fn)
+			}
+			total += partial.value
+		}
+		return true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if fn != "AVG" {
+		return total, nil
+	}
+	if len(averages) == 1 && !averages[0].hasCount {
+		return averages[0].value, nil
+	}
+	for _, partial := range averages {
+		if !partial.hasCount {
+			return 0, Error("interstellar: AVG requires partial results with a sum and count")
+		}
+		sum += partial.sum
+		count += partial.count
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	return sum / count, nil
 }
 
 // GetRaw retrieves the raw document
 func (c *DocumentClient) GetRaw(ctx context.Context, opts RequestOptions) ([]byte, *ResponseMetadata, error) {
 	rl := c.ResourceLink()
-	return c.Client.GetResource(ctx, ClientRequest{
+	body, meta, err := c.Client.GetResource(ctx, ClientRequest{
 		Path:         fmt.Sprintf("/%s", rl),
 		ResourceLink: rl,
 		ResourceType: ResourceDocuments,
-		Options:      c.addPartitionKey(opts),
+		Options:      c.addPartitionKey(withConsistency(c.ConsistencyLevel, opts)),
 	})
+	if err == nil && c.StripSystemProperties {
+		body = StripSystemProperties(body)
+	}
+	return body, meta, err
 }
 
 // Get retrieves the raw document and unmarshalls the content into the given value
@@ -203,6 +692,109 @@ func (c *DocumentClient) Get(ctx context.Context, opts RequestOptions, v interfa
 	return meta, nil
 }
 
+// GetIfNoneMatch retrieves the document only if its ETag does not match the given etag, and unmarshalls the content into the given value
+// If the document is unchanged, ErrResourceNotModified is returned and v is left untouched.
+// This avoids transferring the document body (and the RU cost of reading it) when a cached copy is still current.
+func (c *DocumentClient) GetIfNoneMatch(ctx context.Context, etag string, v interface{}) (*ResponseMetadata, error) {
+	return c.Get(ctx, &CommonRequestOptions{IfNoneMatch: etag}, v)
+}
+
+// GetIfModifiedSince retrieves the document only if it has been modified since the given time, and unmarshalls the content into the given value
+// If the document is unchanged, ErrResourceNotModified is returned and v is left untouched.
+func (c *DocumentClient) GetIfModifiedSince(ctx context.Context, since time.Time, v interface{}) (*ResponseMetadata, error) {
+	return c.Get(ctx, &CommonRequestOptions{IfModifiedSince: since}, v)
+}
+
+// PartitionKeyMismatchError is returned by GetOrLocate when the document could not be read with the DocumentClient's partition key,
+// but a document with the same ID exists in the collection under a different partition key.
+type PartitionKeyMismatchError struct {
+	// DocumentID is the ID of the document
+	DocumentID string
+	// PartitionKey is the partition key that was used for the point read
+	PartitionKey []string
+	// Actual is the partition key of the document that was located
+	Actual []string
+}
+
+// Error implements the error interface
+func (e *PartitionKeyMismatchError) Error() string {
+	expected, _ := json.Marshal(e.PartitionKey)
+	actual, _ := json.Marshal(e.Actual)
+	return fmt.Sprintf("interstellar: document '%s' was not found with partition key %s, but exists with partition key %s", e.DocumentID, expected, actual)
+}
+
+// Cause returns ErrResourceNotFound, since the point read of the document failed
+func (e *PartitionKeyMismatchError) Cause() error {
+	return ErrResourceNotFound
+}
+
+// GetOrLocate retrieves the document like Get, but if the point read returns ErrResourceNotFound,
+// it runs a cross-partition query for the document ID to find out if the document exists under a different partition key.
+// If it does, a *PartitionKeyMismatchError reporting the correct partition key is returned; otherwise ErrResourceNotFound is returned.
+//
+// This is a debugging aid for partition key plumbing bugs. It is opt-in because the fallback reads the collection
+// and runs a cross-partition query, which costs considerably more RU than the point read.
+func (c *DocumentClient) GetOrLocate(ctx context.Context, opts RequestOptions, v interface{}) (*ResponseMetadata, error) {
+	meta, err := c.Get(ctx, opts, v)
+	if err != ErrResourceNotFound {
+		return meta, err
+	}
+	actual, found, lerr := c.LocatePartitionKey(ctx)
+	if lerr != nil || !found {
+		return meta, err
+	}
+	return meta, &PartitionKeyMismatchError{
+		DocumentID:   c.DocumentID,
+		PartitionKey: c.PartitionKey,
+		Actual:       actual,
+	}
+}
+
+// LocatePartitionKey finds the partition key of the document with a cross-partition query on the document ID
+// Returns false if the document does not exist in the collection.
+func (c *DocumentClient) LocatePartitionKey(ctx context.Context) ([]string, bool, error) {
+	cc := &CollectionClient{
+		Client:           c.Client,
+		DatabaseID:       c.DatabaseID,
+		CollectionID:     c.CollectionID,
+		ConsistencyLevel: c.ConsistencyLevel,
+	}
+	coll, _, err := cc.Get(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	query := &Query{
+		Query:                "SELECT * FROM c WHERE c.id = @id",
+		EnableCrossPartition: true,
+	}
+	query.AddParameter("@id", c.DocumentID)
+	var doc json.RawMessage
+	err = cc.QueryDocumentsRaw(ctx, query, func(resList []json.RawMessage, meta ResponseMetadata) (bool, error) {
+		if len(resList) > 0 {
+			doc = resList[0]
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil || doc == nil {
+		return nil, false, err
+	}
+	if coll.PartitionKey == nil {
+		return nil, true, nil
+	}
+	body, err := decodeDocument(doc)
+	if err != nil {
+		return nil, true, err
+	}
+	pk := make([]string, len(coll.PartitionKey.Paths))
+	for i, path := range coll.PartitionKey.Paths {
+		if v, ok := documentValueAt(body, path); ok && v != nil {
+			pk[i] = partitionKeyValue(v)
+		}
+	}
+	return pk, true, nil
+}
+
 // Delete removes the document from the collection
 func (c *DocumentClient) Delete(ctx context.Context, opts RequestOptions) (bool, *ResponseMetadata, error) {
 	rl := c.ResourceLink()
@@ -214,8 +806,25 @@ func (c *DocumentClient) Delete(ctx context.Context, opts RequestOptions) (bool,
 	})
 }
 
+// DeleteIfExists removes the document from the collection, and treats a document which does not exist as already deleted
+// Returns false (and no error) if the document did not exist.
+func (c *DocumentClient) DeleteIfExists(ctx context.Context, opts RequestOptions) (bool, *ResponseMetadata, error) {
+	ok, meta, err := c.Delete(ctx, opts)
+	if err == ErrResourceNotFound {
+		return false, meta, nil
+	}
+	return ok, meta, err
+}
+
+// ErrPartitionKeyRequired is returned when a document in a partitioned collection is replaced without a partition key
+const ErrPartitionKeyRequired = Error("interstellar: a partition key is required to replace a document in a partitioned collection")
+
 // ReplaceDocumentRequest are parameters for CreateDocument
 type ReplaceDocumentRequest struct {
+	// PartitionKey of the document for partitioned collections
+	// If empty, the PartitionKey of the DocumentClient is used.
+	PartitionKey []string
+
 	// ETag is used for optimistic concurrency. If set, the ETag value of the existing document must match this in order for the operation to complete.
 	ETag string
 
@@ -234,16 +843,46 @@ type ReplaceDocumentRequest struct {
 
 	// Unmarshaler is an optional Unmarshaler that will be called with the response body
 	Unmarshaler json.Unmarshaler
+
+	// StripSystemProperties removes the system properties (such as _rid and _etag) from the document before it is sent
+	// Set the ETag field for optimistic concurrency, since the _etag in the body is not sent. See StripSystemProperties
+	StripSystemProperties bool
+
+	// AllowTentativeWrites allows the write to be accepted tentatively on multi-master accounts. See HeaderAllowTentativeWrites
+	AllowTentativeWrites bool
+
+	// Validator is an optional function that will be called with the marshalled document before it is sent.
+	// If it returns an error, the document is not replaced and the error is returned. See RequiredFields for a simple validator.
+	Validator DocumentValidator
+
+	// ReturnMinimal asks for the replaced document to be omitted from the response, to save bandwidth when it is not needed
+	// The response body is then empty (and the Unmarshaler is not called), but the ResponseMetadata (including the ETag) is still returned.
+	ReturnMinimal bool
+
+	// MaxDocumentBytes is the size limit of the marshalled document, checked before it is sent, so a larger document fails with ErrPayloadTooLarge
+	// If zero, DefaultMaxDocumentBytes is used; if negative, the document is sent regardless of its size.
+	MaxDocumentBytes int
 }
 
 func (r ReplaceDocumentRequest) json() ([]byte, error) {
 	if r.Body == nil && r.Document == nil {
 		return nil, Error("interstellar: must set either a Document or a Body for ReplaceDocumentRequest")
 	}
-	if len(r.Body) == 0 {
-		return json.Marshal(r.Document)
+	body := r.Body
+	if len(body) == 0 {
+		b, err := json.Marshal(r.Document)
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+	if r.StripSystemProperties {
+		body = StripSystemProperties(body)
+	}
+	if err := checkDocumentSize(body, r.MaxDocumentBytes); err != nil {
+		return nil, err
 	}
-	return r.Body, nil
+	return body, nil
 }
 
 // ApplyOptions applies the request options to the api request
@@ -254,6 +893,12 @@ func (r ReplaceDocumentRequest) ApplyOptions(req *http.Request) {
 	if r.IndexingDirective != nil {
 		req.Header.Set(HeaderIndexingDirective, string(*r.IndexingDirective))
 	}
+	if r.AllowTentativeWrites {
+		req.Header.Set(HeaderAllowTentativeWrites, "true")
+	}
+	if r.ReturnMinimal {
+		req.Header.Set(HeaderPrefer, PreferReturnMinimal)
+	}
 	if r.Options != nil {
 		r.Options.ApplyOptions(req)
 	}
@@ -261,26 +906,49 @@ func (r ReplaceDocumentRequest) ApplyOptions(req *http.Request) {
 
 // ReplaceDocument replaces this document
 func (c *DocumentClient) ReplaceDocument(ctx context.Context, req ReplaceDocumentRequest) ([]byte, *ResponseMetadata, error) {
+	if req.IndexingDirective == nil {
+		req.IndexingDirective = c.IndexingDirective
+	}
+	pk := req.PartitionKey
+	if len(pk) == 0 {
+		pk = c.PartitionKey
+	}
+	if c.Partitioned && len(pk) == 0 {
+		return nil, nil, ErrPartitionKeyRequired
+	}
 	body, err := req.json()
 	if err != nil {
 		return nil, nil, err
 	}
+	if req.Validator != nil {
+		if err = req.Validator(body); err != nil {
+			return nil, nil, errors.Wrap(err, "interstellar: document failed validation")
+		}
+	}
 	rl := c.ResourceLink()
 	data, meta, err := c.Client.CreateOrReplaceResource(ctx, ClientRequest{
 		Method:       http.MethodPut,
 		Path:         fmt.Sprintf("/%s", rl),
 		ResourceLink: rl,
 		ResourceType: ResourceDocuments,
-		Options:      c.addPartitionKey(req),
+		Options:      addPartitionKey(req, pk),
 		Body:         bytes.NewBuffer(body),
 	})
 	if err != nil {
 		return nil, meta, err
 	}
-	if req.Unmarshaler != nil {
+	if req.Unmarshaler != nil && len(data) > 0 {
 		if err = req.Unmarshaler.UnmarshalJSON(data); err != nil {
 			return nil, meta, err
 		}
 	}
 	return data, meta, nil
 }
+
+// WithOptions creates a copy of the DocumentClient which applies the options to every request made by it
+// See Client.WithOptions
+func (c *DocumentClient) WithOptions(opts RequestOptions) *DocumentClient {
+	cc := *c
+	cc.Client = c.Client.WithOptions(opts)
+	return &cc
+}
@@ -0,0 +1,835 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package interstellar_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jet/go-interstellar"
+	"github.com/jet/go-interstellar/internal/testutil"
+	"github.com/pkg/errors"
+)
+
+func TestCreateDocumentValidator(t *testing.T) {
+	examples := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{name: "valid", body: `{"id":"1","address":{"state":"NY"}}`},
+		{name: "missing-id", body: `{"address":{"state":"NY"}}`, expected: "'/id'"},
+		{name: "null-nested", body: `{"id":"1","address":{"state":null}}`, expected: "'address/state'"},
+		{name: "invalid-json", body: `{"id":`, expected: "not valid JSON"},
+	}
+	for _, ex := range examples {
+		t.Run(ex.name, func(t *testing.T) {
+			fake := testutil.NewFakeRequester().On(http.MethodPost, "/dbs/db1/colls/col1/docs", testutil.Status(http.StatusCreated, ex.body))
+			client := testutil.NewStubClient(fake)
+			_, _, err := client.WithDatabase("db1").WithCollection("col1").CreateDocument(context.Background(), interstellar.CreateDocumentRequest{
+				Body:      []byte(ex.body),
+				Validator: interstellar.RequiredFields("/id", "address/state"),
+			})
+			requests := len(fake.Requests())
+			if ex.expected == "" {
+				if err != nil {
+					t.Fatalf("expected validation to pass, got %v", err)
+				}
+				if requests != 1 {
+					t.Fatalf("expected 1 request, got %d", requests)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), ex.expected) {
+				t.Fatalf("expected error containing %s, got %v", ex.expected, err)
+			}
+			if requests != 0 {
+				t.Fatalf("expected no requests to be sent, got %d", requests)
+			}
+		})
+	}
+}
+
+func TestReplaceDocumentValidator(t *testing.T) {
+	fake := testutil.NewFakeRequester().On(http.MethodPut, "/dbs/db1/colls/col1/docs/1", testutil.Page(`{"id":"1"}`, ""))
+	client := testutil.NewStubClient(fake)
+	doc := client.WithDatabase("db1").WithCollection("col1").WithDocument("1", nil)
+	for _, body := range []string{`{"id":"1","tenant":"a"}`, `{"tenant":"a"}`} {
+		_, _, err := doc.ReplaceDocument(context.Background(), interstellar.ReplaceDocumentRequest{
+			Body:      []byte(body),
+			Validator: interstellar.RequiredFields("id"),
+		})
+		if strings.Contains(body, `"id"`) != (err == nil) {
+			t.Errorf("%s: unexpected validation result %v", body, err)
+		}
+	}
+	if requests := len(fake.Requests()); requests != 1 {
+		t.Errorf("expected only the valid document to be sent, got %d requests", requests)
+	}
+}
+
+func TestQueryDocumentsInRange(t *testing.T) {
+	fake := testutil.NewFakeRequester().On(http.MethodPost, "/dbs/db1/colls/col1/docs",
+		testutil.Page(`{"Documents":[{"id":"1"}]}`, "range-3-page-2"),
+		testutil.Page(`{"Documents":[{"id":"1"}]}`, ""))
+	cc := testutil.NewStubClient(fake).WithDatabase("db1").WithCollection("col1")
+	if err := cc.QueryDocumentsInRange(context.Background(), "3", &interstellar.Query{Query: "SELECT * FROM c"}, func(resList []json.RawMessage, meta interstellar.ResponseMetadata) (bool, error) {
+		return true, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	reqs := fake.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(reqs))
+	}
+	for i, cont := range []string{"", "range-3-page-2"} {
+		if rid := reqs[i].Header.Get(interstellar.HeaderDocDBPartitionKeyRangeID); rid != "3" {
+			t.Errorf("request %d: expected partition key range id '3', got '%s'", i, rid)
+		}
+		if actual := reqs[i].Header.Get(interstellar.HeaderContinuation); actual != cont {
+			t.Errorf("request %d: expected continuation '%s', got '%s'", i, cont, actual)
+		}
+	}
+}
+
+func TestDocumentGetIfNoneMatch(t *testing.T) {
+	etag := `"00000000-0000-0000-0000-000000000001"`
+	client := testutil.NewStubClient(testutil.RequesterFunc(func(req *http.Request) (*http.Response, error) {
+		hdr := make(http.Header)
+		hdr.Set(interstellar.HeaderETag, etag)
+		if req.Header.Get(interstellar.HeaderIfNoneMatch) == etag {
+			return testutil.NewResponse(req, http.StatusNotModified, hdr, ""), nil
+		}
+		return testutil.NewResponse(req, http.StatusOK, hdr, `{"id":"1","_etag":"\"00000000-0000-0000-0000-000000000001\""}`), nil
+	}))
+	dc := client.WithDatabase("db1").WithCollection("col1").WithDocument("1", []string{"1"})
+	var doc interstellar.DocumentProperties
+	meta, err := dc.GetIfNoneMatch(context.Background(), `"stale"`, &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.ID != "1" || meta.ETag != etag {
+		t.Fatalf("unexpected document %s", testutil.ToJSON(doc))
+	}
+	if _, err = dc.GetIfNoneMatch(context.Background(), meta.ETag, &doc); err != interstellar.ErrResourceNotModified {
+		t.Fatalf("expected ErrResourceNotModified, got %v", err)
+	}
+}
+
+func TestDocumentGetIfModifiedSince(t *testing.T) {
+	modified := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	client := testutil.NewStubClient(testutil.RequesterFunc(func(req *http.Request) (*http.Response, error) {
+		since, err := time.Parse(http.TimeFormat, req.Header.Get(interstellar.HeaderIfModifiedSince))
+		if err != nil {
+			t.Fatalf("invalid If-Modified-Since header: %v", err)
+		}
+		if !modified.After(since) {
+			return testutil.NewResponse(req, http.StatusNotModified, nil, ""), nil
+		}
+		return testutil.NewResponse(req, http.StatusOK, nil, `{"id":"1"}`), nil
+	}))
+	dc := client.WithDatabase("db1").WithCollection("col1").WithDocument("1", []string{"1"})
+	var doc interstellar.DocumentProperties
+	if _, err := dc.GetIfModifiedSince(context.Background(), modified.Add(-time.Hour), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.ID != "1" {
+		t.Fatalf("unexpected document %s", testutil.ToJSON(doc))
+	}
+	if _, err := dc.GetIfModifiedSince(context.Background(), modified, &doc); err != interstellar.ErrResourceNotModified {
+		t.Fatalf("expected ErrResourceNotModified, got %v", err)
+	}
+}
+
+func TestLocatePartitionKeyValues(t *testing.T) {
+	examples := map[string]string{
+		`"b"`:                  "b",
+		`1000000`:              "1000000",
+		`12345678901234567890`: "12345678901234567890",
+		`2.5`:                  "2.5",
+		`true`:                 "true",
+	}
+	for value, expected := range examples {
+		client := testutil.NewStubClient(testutil.NewFakeRequester().
+			On(http.MethodGet, "/dbs/db1/colls/col1", testutil.Page(`{"id":"col1","partitionKey":{"paths":["/tenant"],"kind":"Hash"}}`, "")).
+			On(http.MethodPost, "/dbs/db1/colls/col1/docs", testutil.Page(`{"Documents":[{"id":"1","tenant":`+value+`}]}`, "")))
+		pk, found, err := client.WithDatabase("db1").WithCollection("col1").WithDocument("1", nil).LocatePartitionKey(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !found || len(pk) != 1 || pk[0] != expected {
+			t.Errorf("%s: expected partition key [%s], got %v", value, expected, pk)
+		}
+	}
+}
+
+func TestDocumentGetOrLocate(t *testing.T) {
+	fake := testutil.NewFakeRequester().
+		On(http.MethodGet, "/dbs/db1/colls/col1/docs/1", testutil.Status(http.StatusNotFound, `{"code":"NotFound"}`)).
+		On(http.MethodGet, "/dbs/db1/colls/col1", testutil.Page(`{"id":"col1","partitionKey":{"paths":["/tenant"],"kind":"Hash"}}`, "")).
+		On(http.MethodPost, "/dbs/db1/colls/col1/docs", testutil.Page(`{"Documents":[{"id":"1","tenant":"b"}]}`, ""))
+	dc := testutil.NewStubClient(fake).WithDatabase("db1").WithCollection("col1").WithDocument("1", []string{"a"})
+	var doc interstellar.DocumentProperties
+	_, err := dc.GetOrLocate(context.Background(), nil, &doc)
+	for _, req := range fake.RequestsTo(http.MethodPost, "/dbs/db1/colls/col1/docs") {
+		if req.Header.Get(interstellar.HeaderDocDBQueryEnableCrossPartition) != "true" {
+			t.Errorf("expected a cross partition query")
+		}
+	}
+	mismatch, ok := err.(*interstellar.PartitionKeyMismatchError)
+	if !ok {
+		t.Fatalf("expected *PartitionKeyMismatchError, got %v", err)
+	}
+	if len(mismatch.Actual) != 1 || mismatch.Actual[0] != "b" {
+		t.Errorf("expected actual partition key [b], got %v", mismatch.Actual)
+	}
+	if mismatch.Cause() != interstellar.ErrResourceNotFound {
+		t.Errorf("expected cause to be ErrResourceNotFound")
+	}
+}
+
+func TestCreateDocumentRequestValidate(t *testing.T) {
+	pk := &interstellar.CollectionPartitionKey{Paths: []string{"/tenant"}, Kind: interstellar.PartititionKindHash}
+	examples := []struct {
+		name  string
+		req   interstellar.CreateDocumentRequest
+		pk    *interstellar.CollectionPartitionKey
+		valid bool
+	}{
+		{name: "valid", req: interstellar.CreateDocumentRequest{Body: []byte(`{"id":"1","tenant":"a"}`), PartitionKey: []string{"a"}}, pk: pk, valid: true},
+		{name: "valid-number", req: interstellar.CreateDocumentRequest{Body: []byte(`{"id":"1","tenant":1000000}`), PartitionKey: []string{"1000000"}}, pk: pk, valid: true},
+		{name: "valid-large-integer", req: interstellar.CreateDocumentRequest{Body: []byte(`{"id":"1","tenant":12345678901234567890}`), PartitionKey: []string{"12345678901234567890"}}, pk: pk, valid: true},
+		{name: "valid-bool", req: interstellar.CreateDocumentRequest{Body: []byte(`{"id":"1","tenant":true}`), PartitionKey: []string{"true"}}, pk: pk, valid: true},
+		{name: "valid-unpartitioned", req: interstellar.CreateDocumentRequest{Document: map[string]string{"id": "1"}}, valid: true},
+		{name: "no-body", req: interstellar.CreateDocumentRequest{PartitionKey: []string{"a"}}, pk: pk},
+		{name: "unmarshallable", req: interstellar.CreateDocumentRequest{Document: func() {}}},
+		{name: "not-object", req: interstellar.CreateDocumentRequest{Body: []byte(`[1,2]`)}},
+		{name: "missing-partition-key", req: interstellar.CreateDocumentRequest{Body: []byte(`{"id":"1","tenant":"a"}`)}, pk: pk},
+		{name: "mismatched-partition-key", req: interstellar.CreateDocumentRequest{Body: []byte(`{"id":"1","tenant":"a"}`), PartitionKey: []string{"b"}}, pk: pk},
+		{name: "unexpected-partition-key", req: interstellar.CreateDocumentRequest{Body: []byte(`{"id":"1"}`), PartitionKey: []string{"a"}}},
+		{name: "validator", req: interstellar.CreateDocumentRequest{Body: []byte(`{"tenant":"a"}`), PartitionKey: []string{"a"}, Validator: interstellar.RequiredFields("id")}, pk: pk},
+	}
+	for _, ex := range examples {
+		t.Run(ex.name, func(t *testing.T) {
+			err := ex.req.Validate(ex.pk)
+			if ex.valid && err != nil {
+				t.Fatalf("expected request to be valid, got %v", err)
+			}
+			if !ex.valid && err == nil {
+				t.Fatalf("expected request to be invalid")
+			}
+		})
+	}
+}
+
+func TestBuildCreateDocument(t *testing.T) {
+	fake := testutil.NewFakeRequester()
+	client := testutil.NewStubClient(fake)
+	req, err := client.WithDatabase("db1").WithCollection("col1").BuildCreateDocument(context.Background(), interstellar.CreateDocumentRequest{
+		Body:         []byte(`{"id":"1","tenant":"a"}`),
+		PartitionKey: []string{"a"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != http.MethodPost || req.URL.Path != "/dbs/db1/colls/col1/docs" {
+		t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+	}
+	if pk := req.Header.Get(interstellar.HeaderDocDBPartitionKey); pk != `["a"]` {
+		t.Errorf("unexpected partition key header '%s'", pk)
+	}
+	if req.Header.Get(interstellar.HeaderAuthorization) == "" {
+		t.Errorf("expected request to be authorized")
+	}
+	if len(fake.Requests()) != 0 {
+		t.Errorf("expected the request not to be sent")
+	}
+}
+
+func TestDocumentIndexingDirective(t *testing.T) {
+	fake := testutil.NewFakeRequester().
+		On(http.MethodPost, "/dbs/db1/colls/col1/docs", testutil.Page(`{"id":"1"}`, "")).
+		On(http.MethodPut, "/dbs/db1/colls/col1/docs/1", testutil.Page(`{"id":"1"}`, ""))
+	client := testutil.NewStubClient(fake)
+	cc := client.WithDatabase("db1").WithCollection("col1")
+	cc.IndexingDirective = interstellar.DocumentIndexingExclude.Ptr()
+	ctx := context.Background()
+	examples := []struct {
+		name     string
+		fn       func() error
+		expected string
+	}{
+		{
+			name: "upsert-default",
+			fn: func() error {
+				_, _, err := cc.CreateDocument(ctx, interstellar.CreateDocumentRequest{Upsert: true, Body: []byte(`{"id":"1"}`)})
+				return err
+			},
+			expected: "Exclude",
+		},
+		{
+			name: "upsert-override",
+			fn: func() error {
+				_, _, err := cc.CreateDocument(ctx, interstellar.CreateDocumentRequest{Upsert: true, IndexingDirective: interstellar.DocumentIndexingInclude.Ptr(), Body: []byte(`{"id":"1"}`)})
+				return err
+			},
+			expected: "Include",
+		},
+		{
+			name: "replace-inherited",
+			fn: func() error {
+				_, _, err := cc.WithDocument("1", nil).ReplaceDocument(ctx, interstellar.ReplaceDocumentRequest{Body: []byte(`{"id":"1"}`)})
+				return err
+			},
+			expected: "Exclude",
+		},
+		{
+			name: "replace-options",
+			fn: func() error {
+				dc := client.WithDatabase("db1").WithCollection("col1").WithDocument("1", nil)
+				_, _, err := dc.ReplaceDocument(ctx, interstellar.ReplaceDocumentRequest{Body: []byte(`{"id":"1"}`), Options: interstellar.DocumentIndexingInclude})
+				return err
+			},
+			expected: "Include",
+		},
+	}
+	for _, ex := range examples {
+		t.Run(ex.name, func(t *testing.T) {
+			if err := ex.fn(); err != nil {
+				t.Fatal(err)
+			}
+			reqs := fake.Requests()
+			if directive := reqs[len(reqs)-1].Header.Get(interstellar.HeaderIndexingDirective); directive != ex.expected {
+				t.Errorf("expected indexing directive '%s', got '%s'", ex.expected, directive)
+			}
+		})
+	}
+}
+
+func TestWriteResult(t *testing.T) {
+	hdr := make(http.Header)
+	hdr.Set(interstellar.HeaderETag, `"header-etag"`)
+	hdr.Set(interstellar.HeaderRequestCharge, "10.29")
+	client := testutil.NewStubClient(testutil.NewFakeRequester().On(http.MethodPut, "/dbs/db1/colls/col1/docs/1", testutil.FakeResponse{
+		StatusCode: http.StatusOK,
+		Header:     hdr,
+		Body:       `{"id":"1","_rid":"Sl8fALN4sw4CAAAAAAAAAA==","_etag":"\"body-etag\""}`,
+	}))
+	body, meta, err := client.WithDatabase("db1").WithCollection("col1").WithDocument("1", nil).ReplaceDocument(context.Background(), interstellar.ReplaceDocumentRequest{
+		ETag: `"previous-etag"`,
+		Body: []byte(`{"id":"1"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wr := interstellar.NewWriteResult(body, meta)
+	if wr.ETag != `"header-etag"` || wr.ResourceID != "Sl8fALN4sw4CAAAAAAAAAA==" || wr.RequestCharge != "10.29" {
+		t.Errorf("unexpected write result %+v", wr)
+	}
+	if etag := interstellar.ParseResourceETag(body); etag != `"body-etag"` {
+		t.Errorf("expected body etag, got '%s'", etag)
+	}
+	if etag := interstellar.ParseResourceETag([]byte(`[]`)); etag != "" {
+		t.Errorf("expected no etag, got '%s'", etag)
+	}
+}
+
+func TestQueryAggregate(t *testing.T) {
+	examples := []struct {
+		name     string
+		query    string
+		pages    []string
+		expected float64
+		err      bool
+	}{
+		{name: "count", query: "SELECT VALUE COUNT(1) FROM c", pages: []string{`[3]`, `[]`, `[4]`}, expected: 7},
+		{name: "sum", query: "select value sum(c.total) from c", pages: []string{`[1.5,2.5]`, `[{"item":6}]`}, expected: 10},
+		{name: "avg", query: "SELECT VALUE AVG(c.age) FROM c", pages: []string{`[{"sum":30,"count":1}]`, `[{"item":{"sum":30,"count":3}}]`}, expected: 15},
+		{name: "avg-empty", query: "SELECT VALUE AVG(c.age) FROM c", pages: []string{`[]`}, expected: 0},
+		{name: "avg-single-partition", query: "SELECT VALUE AVG(c.age) FROM c", pages: []string{`[]`, `[25]`}, expected: 25},
+		{name: "avg-of-averages", query: "SELECT VALUE AVG(c.age) FROM c", pages: []string{`[30]`, `[10]`}, err: true},
+		{name: "not-aggregate", query: "SELECT * FROM c", pages: []string{`[]`}, err: true},
+		{name: "subquery", query: "SELECT * FROM c WHERE c.n > (SELECT VALUE COUNT(1) FROM c.items)", pages: []string{`[]`}, err: true},
+	}
+	for _, ex := range examples {
+		t.Run(ex.name, func(t *testing.T) {
+			var pages []testutil.FakeResponse
+			for i, page := range ex.pages {
+				continuation := "next"
+				if i == len(ex.pages)-1 {
+					continuation = ""
+				}
+				pages = append(pages, testutil.Page(`{"Documents":`+page+`}`, continuation))
+			}
+			client := testutil.NewStubClient(testutil.NewFakeRequester().On(http.MethodPost, "/dbs/db1/colls/col1/docs", pages...))
+			actual, err := client.Collection("db1", "col1").QueryAggregate(context.Background(), &interstellar.Query{
+				Query:                ex.query,
+				EnableCrossPartition: true,
+			})
+			if ex.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != ex.expected {
+				t.Errorf("expected %v, got %v", ex.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDeleteAllDocumentsByPartitionKey(t *testing.T) {
+	const path = "/dbs/db1/colls/col1/operations/partitionkeydelete"
+	fake := testutil.NewFakeRequester().On(http.MethodPost, path, testutil.Page("", ""))
+	coll := testutil.NewStubClient(fake).WithDatabase("db1").WithCollection("col1")
+	if _, err := coll.DeleteAllDocumentsByPartitionKey(context.Background(), []string{"tenant1"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	reqs := fake.RequestsTo(http.MethodPost, path)
+	if len(reqs) != 1 {
+		t.Fatalf("unexpected requests %s", testutil.ToJSON(fake.Requests()))
+	}
+	if pk := reqs[0].Header.Get(interstellar.HeaderDocDBPartitionKey); pk != `["tenant1"]` {
+		t.Errorf("unexpected partition key header: %s", pk)
+	}
+	if v := reqs[0].Header.Get(interstellar.HeaderMSAPIVersion); v != interstellar.PartitionKeyDeleteAPIVersion {
+		t.Errorf("unexpected API version: %s", v)
+	}
+	if _, err := coll.DeleteAllDocumentsByPartitionKey(context.Background(), nil, nil); err == nil {
+		t.Error("expected an error without a partition key")
+	}
+}
+
+func TestStripSystemProperties(t *testing.T) {
+	tests := map[string]string{
+		`{"id":"doc1","_rid":"rid","_self":"self","_ts":1,"_attachments":"attachments/","_etag":"\"etag\"","name":"a"}`: `{"id":"doc1","name":"a"}`,
+		`{"id":"doc1"}`: `{"id":"doc1"}`,
+		`[1,2]`:         `[1,2]`,
+		`not json`:      `not json`,
+	}
+	for raw, expected := range tests {
+		if actual := string(interstellar.StripSystemProperties([]byte(raw))); actual != expected {
+			t.Errorf("%s: expected %s, got %s", raw, expected, actual)
+		}
+	}
+	fake := testutil.NewFakeRequester().On(http.MethodPut, "/dbs/db1/colls/col1/docs/doc1", testutil.Page(`{"id":"doc1","name":"a"}`, ""))
+	client := testutil.NewStubClient(fake)
+	doc := struct {
+		interstellar.DocumentProperties
+		Name string `json:"name"`
+	}{interstellar.DocumentProperties{ID: "doc1", ETag: `"etag"`, ResourceID: "rid"}, "a"}
+	_, _, err := client.WithDatabase("db1").WithCollection("col1").WithDocument("doc1", nil).ReplaceDocument(context.Background(), interstellar.ReplaceDocumentRequest{
+		ETag:                  doc.ETag,
+		Document:              doc,
+		StripSystemProperties: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent := fake.Requests()[0].Body; sent != `{"id":"doc1","name":"a"}` {
+		t.Errorf("unexpected body sent: %s", sent)
+	}
+}
+
+func TestDocumentAllowTentativeWrites(t *testing.T) {
+	fake := testutil.NewFakeRequester().
+		On(http.MethodPost, "/dbs/db1/colls/col1/docs", testutil.Page(`{"id":"doc1"}`, "")).
+		On(http.MethodPut, "/dbs/db1/colls/col1/docs/doc1", testutil.Page(`{"id":"doc1"}`, ""))
+	client := testutil.NewStubClient(fake)
+	cc := client.WithDatabase("db1").WithCollection("col1")
+	ctx := context.Background()
+	if _, _, err := cc.CreateDocument(ctx, interstellar.CreateDocumentRequest{Body: []byte(`{"id":"doc1"}`), AllowTentativeWrites: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cc.CreateDocument(ctx, interstellar.CreateDocumentRequest{Body: []byte(`{"id":"doc1"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cc.WithDocument("doc1", nil).ReplaceDocument(ctx, interstellar.ReplaceDocumentRequest{Body: []byte(`{"id":"doc1"}`), AllowTentativeWrites: true}); err != nil {
+		t.Fatal(err)
+	}
+	var tentative []string
+	for _, req := range fake.Requests() {
+		tentative = append(tentative, req.Header.Get(interstellar.HeaderAllowTentativeWrites))
+	}
+	if len(tentative) != 3 || tentative[0] != "true" || tentative[1] != "" || tentative[2] != "true" {
+		t.Errorf("unexpected %s headers: %v", interstellar.HeaderAllowTentativeWrites, tentative)
+	}
+}
+
+func TestDocumentConsistencyLevel(t *testing.T) {
+	page := testutil.Page(`{"id":"doc1","Documents":[]}`, "")
+	fake := testutil.NewFakeRequester().
+		On(http.MethodPost, "/dbs/db1/colls/col1/docs", page).
+		On(http.MethodGet, "/dbs/db1/colls/col1/docs", page).
+		On(http.MethodGet, "/dbs/db1/colls/col1/docs/doc1", page)
+	client := testutil.NewStubClient(fake)
+	ctx := context.Background()
+	cc := client.WithDatabase("db1").WithCollection("col1")
+	cc.ConsistencyLevel = interstellar.ConsistencyEventual
+	noop := func(resList []json.RawMessage, meta interstellar.ResponseMetadata) (bool, error) {
+		return true, nil
+	}
+	if err := cc.QueryDocumentsRaw(ctx, &interstellar.Query{Query: "SELECT * FROM c"}, noop); err != nil {
+		t.Fatal(err)
+	}
+	if err := cc.QueryDocumentsRaw(ctx, &interstellar.Query{Query: "SELECT * FROM c", ConsistencyLevel: interstellar.ConsistencySession}, noop); err != nil {
+		t.Fatal(err)
+	}
+	if err := cc.ListDocumentsRaw(ctx, nil, noop); err != nil {
+		t.Fatal(err)
+	}
+	doc := cc.WithDocument("doc1", nil)
+	if _, _, err := doc.GetRaw(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := doc.GetRaw(ctx, interstellar.ConsistencySession); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.WithDatabase("db1").WithCollection("col1").WithDocument("doc1", nil).GetRaw(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"Eventual", "Session", "Eventual", "Eventual", "Session", ""}
+	var levels []string
+	for _, req := range fake.Requests() {
+		levels = append(levels, req.Header.Get(interstellar.HeaderConsistencyLevel))
+	}
+	if len(levels) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, levels)
+	}
+	for i := range expected {
+		if levels[i] != expected[i] {
+			t.Errorf("request %d: expected consistency %q, got %q", i, expected[i], levels[i])
+		}
+	}
+}
+
+type unmarshalRecorder struct {
+	calls int
+}
+
+func (u *unmarshalRecorder) UnmarshalJSON(data []byte) error {
+	u.calls++
+	return nil
+}
+
+func TestDocumentReturnMinimal(t *testing.T) {
+	client := testutil.NewStubClient(testutil.RequesterFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get(interstellar.HeaderPrefer) != interstellar.PreferReturnMinimal {
+			return testutil.NewResponse(req, http.StatusOK, nil, `{"id":"1"}`), nil
+		}
+		hdr := make(http.Header)
+		hdr.Set(interstellar.HeaderETag, `"etag1"`)
+		status := http.StatusCreated
+		if req.Method == http.MethodPut {
+			status = http.StatusNoContent
+		}
+		return testutil.NewResponse(req, status, hdr, ``), nil
+	}))
+	ctx := context.Background()
+	cc := client.WithDatabase("db1").WithCollection("col1")
+	var u unmarshalRecorder
+	body, meta, err := cc.CreateDocument(ctx, interstellar.CreateDocumentRequest{Body: []byte(`{"id":"1"}`), ReturnMinimal: true, Unmarshaler: &u})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(body) != 0 || meta.ETag != `"etag1"` || u.calls != 0 {
+		t.Errorf("unexpected create result: body=%q etag=%s unmarshal calls=%d", body, meta.ETag, u.calls)
+	}
+	body, meta, err = cc.WithDocument("1", nil).ReplaceDocument(ctx, interstellar.ReplaceDocumentRequest{Body: []byte(`{"id":"1"}`), ReturnMinimal: true, Unmarshaler: &u})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(body) != 0 || meta.ETag != `"etag1"` || u.calls != 0 {
+		t.Errorf("unexpected replace result: body=%q etag=%s unmarshal calls=%d", body, meta.ETag, u.calls)
+	}
+	if _, _, err = cc.CreateDocument(ctx, interstellar.CreateDocumentRequest{Body: []byte(`{"id":"1"}`), Unmarshaler: &u}); err != nil {
+		t.Fatal(err)
+	}
+	if u.calls != 1 {
+		t.Errorf("expected the full response to be unmarshalled, got %d calls", u.calls)
+	}
+}
+
+func TestReplaceDocumentPartitionKey(t *testing.T) {
+	fake := testutil.NewFakeRequester().On(http.MethodPut, "/dbs/db1/colls/col1/docs/doc1", testutil.Page(`{"id":"doc1"}`, ""))
+	coll := testutil.NewStubClient(fake).WithDatabase("db1").WithCollection("col1")
+	coll.Partitioned = true
+
+	doc := coll.WithDocument("doc1", nil)
+	if _, _, err := doc.ReplaceDocument(context.Background(), interstellar.ReplaceDocumentRequest{Body: []byte(`{"id":"doc1"}`)}); err != interstellar.ErrPartitionKeyRequired {
+		t.Errorf("expected ErrPartitionKeyRequired, got %v", err)
+	}
+	if len(fake.Requests()) != 0 {
+		t.Fatal("expected no request to be sent without a partition key")
+	}
+	if _, _, err := doc.ReplaceDocument(context.Background(), interstellar.ReplaceDocumentRequest{
+		PartitionKey: []string{"pk1"},
+		Body:         []byte(`{"id":"doc1"}`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := coll.WithDocument("doc1", []string{"pk2"}).ReplaceDocument(context.Background(), interstellar.ReplaceDocumentRequest{Body: []byte(`{"id":"doc1"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	var sent []string
+	for _, req := range fake.Requests() {
+		sent = append(sent, req.Header.Get(interstellar.HeaderDocDBPartitionKey))
+	}
+	if len(sent) != 2 || sent[0] != `["pk1"]` || sent[1] != `["pk2"]` {
+		t.Errorf("unexpected partition keys sent: %v", sent)
+	}
+}
+
+func TestStreamDocumentsRaw(t *testing.T) {
+	fake := testutil.NewFakeRequester().
+		On(http.MethodGet, "/dbs/db1/colls/col1/docs",
+			testutil.Page(`{"Documents":[{"id":"1"},{"id":"2"}]}`, "page2"),
+			testutil.Page(`{"Documents":[{"id":"3"}]}`, ""),
+		)
+	coll := testutil.NewStubClient(fake).WithDatabase("db1").WithCollection("col1")
+	docs, errc := coll.StreamDocumentsRaw(context.Background(), nil)
+	var ids []string
+	for doc := range docs {
+		var d interstellar.DocumentProperties
+		if err := json.Unmarshal(doc, &d); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, d.ID)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(ids, ",") != "1,2,3" {
+		t.Errorf("unexpected documents: %v", ids)
+	}
+
+	fake = testutil.NewFakeRequester().
+		On(http.MethodGet, "/dbs/db1/colls/col1/docs", testutil.Page(`{"Documents":[{"id":"1"},{"id":"2"},{"id":"3"}]}`, ""))
+	coll = testutil.NewStubClient(fake).WithDatabase("db1").WithCollection("col1")
+	ctx, cancel := context.WithCancel(context.Background())
+	docs, errc = coll.StreamDocumentsRaw(ctx, nil)
+	<-docs
+	cancel()
+	for range docs {
+	}
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("expected the context to be cancelled, got %v", err)
+	}
+}
+
+func TestDocumentMaxDocumentBytes(t *testing.T) {
+	fake := testutil.NewFakeRequester().On(http.MethodPost, "/dbs/db1/colls/col1/docs", testutil.Status(http.StatusCreated, `{"id":"doc1"}`))
+	coll := testutil.NewStubClient(fake).WithDatabase("db1").WithCollection("col1")
+	large := []byte(`{"id":"doc1","data":"` + strings.Repeat("x", interstellar.DefaultMaxDocumentBytes) + `"}`)
+	_, _, err := coll.CreateDocument(context.Background(), interstellar.CreateDocumentRequest{Body: large})
+	if err != interstellar.ErrPayloadTooLarge {
+		t.Errorf("expected ErrPayloadTooLarge, got %v", err)
+	}
+	_, _, err = coll.WithDocument("doc1", nil).ReplaceDocument(context.Background(), interstellar.ReplaceDocumentRequest{Body: large})
+	if err != interstellar.ErrDocumentTooLarge {
+		t.Errorf("expected ErrPayloadTooLarge, got %v", err)
+	}
+	if requests := len(fake.Requests()); requests != 0 {
+		t.Fatalf("expected no requests for documents which are too large, got %d", requests)
+	}
+	_, _, err = coll.CreateDocument(context.Background(), interstellar.CreateDocumentRequest{Body: []byte(`{"id":"doc1"}`)})
+	if err != nil || len(fake.Requests()) != 1 {
+		t.Errorf("expected a small document to be sent: %v", err)
+	}
+	_, _, err = coll.CreateDocument(context.Background(), interstellar.CreateDocumentRequest{Body: large, MaxDocumentBytes: -1})
+	if err != nil || len(fake.Requests()) != 2 {
+		t.Errorf("expected the size check to be disabled: %v", err)
+	}
+}
+
+func TestReadDocumentsByIDs(t *testing.T) {
+	var body struct {
+		Query      string `json:"query"`
+		Parameters []struct {
+			Name  string   `json:"name"`
+			Value []string `json:"value"`
+		} `json:"parameters"`
+	}
+	fake := testutil.NewFakeRequester().On(http.MethodPost, "/dbs/db1/colls/col1/docs", testutil.Page(`{"Documents":[{"id":"a"},{"id":"c"}]}`, ""))
+	coll := testutil.NewStubClient(fake).WithDatabase("db1").WithCollection("col1")
+	docs, err := coll.ReadDocumentsByIDs(context.Background(), []string{"pk1"}, []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := fake.Requests()[0]
+	if err = json.Unmarshal([]byte(req.Body), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 {
+		t.Errorf("expected 2 documents, got %d", len(docs))
+	}
+	if pk := req.Header.Get(interstellar.HeaderDocDBPartitionKey); pk != `["pk1"]` {
+		t.Errorf("expected the query to be scoped to the partition key, got '%s'", pk)
+	}
+	if body.Query != "SELECT * FROM c WHERE ARRAY_CONTAINS(@ids, c.id)" || len(body.Parameters) != 1 || strings.Join(body.Parameters[0].Value, ",") != "a,b,c" {
+		t.Errorf("unexpected query %s", testutil.ToJSON(body))
+	}
+}
+
+func TestListDocumentsProjected(t *testing.T) {
+	fake := testutil.NewFakeRequester().On(http.MethodPost, "/dbs/db1/colls/col1/docs", testutil.Page(`{"Documents":[{"id":"a","city":"Hoboken"}]}`, ""))
+	coll := testutil.NewStubClient(fake).WithDatabase("db1").WithCollection("col1")
+	var docs []json.RawMessage
+	err := coll.ListDocumentsProjected(context.Background(), []string{"id", "value", "billing.city", "shipping.city"}, func(resList []json.RawMessage, meta interstellar.ResponseMetadata) (bool, error) {
+		docs = append(docs, resList...)
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var query interstellar.Query
+	if err = json.Unmarshal([]byte(fake.Requests()[0].Body), &query); err != nil {
+		t.Fatal(err)
+	}
+	if query.Query != `SELECT c["id"], c["value"], c["billing"]["city"] AS billing_city, c["shipping"]["city"] AS shipping_city FROM c` {
+		t.Errorf("unexpected query '%s'", query.Query)
+	}
+	if len(docs) != 1 {
+		t.Errorf("expected 1 document, got %d", len(docs))
+	}
+	for _, projection := range [][]string{nil, {"a..b"}, {"a.b", "a_b"}, {"a-b.c", "a_b.c"}} {
+		if _, err := interstellar.NewProjectionQuery(projection); err == nil {
+			t.Errorf("expected projection %q to be invalid", projection)
+		}
+	}
+	query2, err := interstellar.NewProjectionQuery([]string{`name" FROM c; --`, "9lives.x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query2.Query != `SELECT c["name\" FROM c; --"], c["9lives"]["x"] AS _9lives_x FROM c` {
+		t.Errorf("expected property names to be quoted, got '%s'", query2.Query)
+	}
+}
+
+func TestStripSystemPropertiesOnRead(t *testing.T) {
+	const doc = `{"id":"doc1","_rid":"abc","_self":"dbs/x/colls/y/docs/z","_ts":1,"_etag":"\"1\"","_attachments":"attachments/"}`
+	hdr := make(http.Header)
+	hdr.Set(interstellar.HeaderETag, `"1"`)
+	client := testutil.NewStubClient(testutil.NewFakeRequester().
+		On(http.MethodGet, "/dbs/db1/colls/col1/docs/doc1", testutil.FakeResponse{StatusCode: http.StatusOK, Header: hdr, Body: doc}).
+		On(http.MethodGet, "/dbs/db1/colls/col1/docs", testutil.Page(`{"Documents":[`+doc+`]}`, "")).
+		On(http.MethodPost, "/dbs/db1/colls/col1/docs", testutil.Page(`{"Documents":[`+doc+`]}`, "")))
+	coll := client.WithDatabase("db1").WithCollection("col1")
+	coll.StripSystemProperties = true
+	body, meta, err := coll.WithDocument("doc1", nil).GetRaw(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"id":"doc1"}` {
+		t.Errorf("expected the system properties to be stripped, got %s", body)
+	}
+	if meta.ETag != `"1"` {
+		t.Errorf("expected the ETag in the metadata, got '%s'", meta.ETag)
+	}
+	err = coll.ListDocumentsRaw(context.Background(), nil, func(resList []json.RawMessage, meta interstellar.ResponseMetadata) (bool, error) {
+		for _, res := range resList {
+			if string(res) != `{"id":"doc1"}` {
+				t.Errorf("expected the system properties to be stripped, got %s", res)
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for query, expected := range map[string]string{
+		"select top 10 * from c":      `{"id":"doc1"}`,
+		"SELECT c.id, c._ts FROM c":   doc,
+		"SELECT VALUE c._etag FROM c": doc,
+	} {
+		err = coll.QueryDocumentsRaw(context.Background(), &interstellar.Query{Query: query}, func(resList []json.RawMessage, meta interstellar.ResponseMetadata) (bool, error) {
+			for _, res := range resList {
+				if string(res) != expected {
+					t.Errorf("%s: expected %s, got %s", query, expected, res)
+				}
+			}
+			return true, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	coll.StripSystemProperties = false
+	if body, _, _ = coll.WithDocument("doc1", nil).GetRaw(context.Background(), nil); string(body) != doc {
+		t.Errorf("expected the document unchanged by default, got %s", body)
+	}
+}
+
+func TestQueryOneDocument(t *testing.T) {
+	type doc struct {
+		ID string `json:"id"`
+	}
+	examples := []struct {
+		name   string
+		pages  []testutil.FakeResponse
+		found  bool
+		strict error
+		id     string
+	}{
+		{name: "empty", pages: []testutil.FakeResponse{testutil.Page(`{"Documents":[]}`, "")}},
+		{name: "one", pages: []testutil.FakeResponse{testutil.Page(`{"Documents":[{"id":"a"}]}`, "")}, found: true, id: "a"},
+		{name: "empty page first", pages: []testutil.FakeResponse{
+			testutil.Page(`{"Documents":[]}`, "p2"),
+			testutil.Page(`{"Documents":[{"id":"b"}]}`, ""),
+		}, found: true, id: "b"},
+		{name: "many", pages: []testutil.FakeResponse{
+			testutil.Page(`{"Documents":[{"id":"a"}]}`, "p2"),
+			testutil.Page(`{"Documents":[{"id":"b"}]}`, ""),
+		}, found: true, strict: interstellar.ErrMultipleResults, id: "a"},
+	}
+	for _, ex := range examples {
+		t.Run(ex.name, func(t *testing.T) {
+			newCollection := func() *interstellar.CollectionClient {
+				fake := testutil.NewFakeRequester().On(http.MethodPost, "/dbs/db1/colls/col1/docs", ex.pages...)
+				return testutil.NewStubClient(fake).WithDatabase("db1").WithCollection("col1")
+			}
+			query := &interstellar.Query{Query: "SELECT * FROM c WHERE c.email = @email"}
+			query.AddParameter("@email", "someone@example.com")
+
+			var one doc
+			found, _, err := newCollection().QueryOneDocument(context.Background(), query, &one)
+			if errors.Cause(err) != ex.strict {
+				t.Fatalf("expected error %v, got %v", ex.strict, err)
+			}
+			if ex.strict == nil && (found != ex.found || one.ID != ex.id) {
+				t.Errorf("QueryOneDocument: expected found=%v id=%s, got found=%v id=%s", ex.found, ex.id, found, one.ID)
+			}
+
+			var first doc
+			found, _, err = newCollection().QueryFirstDocument(context.Background(), query, &first)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found != ex.found || first.ID != ex.id {
+				t.Errorf("QueryFirstDocument: expected found=%v id=%s, got found=%v id=%s", ex.found, ex.id, found, first.ID)
+			}
+		})
+	}
+}
@@ -16,6 +16,15 @@
 
 package interstellar
 
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
 // DocumentProperties are the well-known properties that may exist on a Document resources
 type DocumentProperties struct {
 	ID          string `json:"id"`
@@ -26,6 +35,90 @@ type DocumentProperties struct {
 	Attachments string `json:"_attachments"`
 }
 
+// WriteResult summarizes the resource returned by a create, upsert or replace
+// This saves read-modify-write loops from parsing the response body to get the ETag for the next update.
+type WriteResult struct {
+	// ETag is the new ETag of the resource
+	ETag string
+	// ResourceID is the _rid of the resource
+	ResourceID string
+	// RequestCharge is the request units (RU) charged for the write
+	RequestCharge string
+}
+
+// NewWriteResult creates a WriteResult from the body and metadata returned by a write, such as CreateDocument or ReplaceDocument
+// The ETag is taken from the response metadata, and falls back to the _etag property of the body.
+func NewWriteResult(body []byte, meta *ResponseMetadata) WriteResult {
+	var res struct {
+		ETag       string `json:"_etag"`
+		ResourceID string `json:"_rid"`
+	}
+	// the body is best-effort, since it may be empty or not an object
+	_ = json.Unmarshal(body, &res)
+	wr := WriteResult{
+		ETag:       res.ETag,
+		ResourceID: res.ResourceID,
+	}
+	if meta != nil {
+		if meta.ETag != "" {
+			wr.ETag = meta.ETag
+		}
+		wr.RequestCharge = meta.RequestCharge
+	}
+	return wr
+}
+
+// ParseResourceETag gets the _etag property from the JSON body of a resource
+// An empty string is returned if the body is not a JSON object, or has no _etag.
+func ParseResourceETag(body []byte) string {
+	return NewWriteResult(body, nil).ETag
+}
+
+// SystemProperties are the properties of a resource which are generated by CosmosDB
+// They are removed by StripSystemProperties.
+var SystemProperties = []string{"_rid", "_self", "_ts", "_attachments", "_etag"}
+
+// StripSystemProperties removes the SystemProperties from the JSON body of a document, such as before it is replaced
+// CosmosDB ignores these properties when they are written, so sending them only wastes bandwidth.
+// The body is returned unchanged if it is not a JSON object. Note that the order of the remaining properties is not preserved.
+//
+// Note: The _etag is removed as well, so read it first (such as with ParseResourceETag) if it is needed for optimistic concurrency;
+// the If-Match header (such as ReplaceDocumentRequest.ETag) is what is checked by the server, not the _etag in the body.
+func StripSystemProperties(raw []byte) []byte {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &doc); err != nil || doc == nil {
+		return raw
+	}
+	stripped := false
+	for _, prop := range SystemProperties {
+		if _, ok := doc[prop]; ok {
+			delete(doc, prop)
+			stripped = true
+		}
+	}
+	if !stripped {
+		return raw
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return raw
+	}
+	return b
+}
+
+// stripSystemPropertiesFrom wraps the pagination function to strip the SystemProperties from each resource, if strip is set
+func stripSystemPropertiesFrom(strip bool, fn PaginateRawResources) PaginateRawResources {
+	if !strip {
+		return fn
+	}
+	return func(resList []json.RawMessage, meta ResponseMetadata) (bool, error) {
+		for i, res := range resList {
+			resList[i] = StripSystemProperties(res)
+		}
+		return fn(resList, meta)
+	}
+}
+
 // DocumentIndexingDirective determines if a document create/update should be indexed
 type DocumentIndexingDirective string
 
@@ -35,3 +128,75 @@ const (
 	// DocumentIndexingExclude specifies that the document should be excluded from the collection index.
 	DocumentIndexingExclude = DocumentIndexingDirective("Exclude")
 )
+
+// Ptr returns a pointer to a copy of the directive, since the IndexingDirective fields of requests and clients are pointers:
+//
+//     req := interstellar.CreateDocumentRequest{IndexingDirective: interstellar.DocumentIndexingExclude.Ptr()}
+//
+func (d DocumentIndexingDirective) Ptr() *DocumentIndexingDirective {
+	return &d
+}
+
+// ApplyOptions sets the indexing directive header, so the directive can be passed as the RequestOptions of a single operation
+func (d DocumentIndexingDirective) ApplyOptions(req *http.Request) {
+	if d != "" {
+		req.Header.Set(HeaderIndexingDirective, string(d))
+	}
+}
+
+// DocumentValidator validates the marshalled JSON body of a document before it is written
+// Returning a non-nil error prevents the document from being sent to the API.
+type DocumentValidator func(body []byte) error
+
+// RequiredFields creates a DocumentValidator which fails when any of the given fields are missing or null in the document.
+// Fields are given as paths, like partition key paths, such as "/id" or "/address/state".
+// The leading '/' is optional.
+func RequiredFields(fields ...string) DocumentValidator {
+	return func(body []byte) error {
+		var doc interface{}
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return errors.Wrap(err, "interstellar: document is not valid JSON")
+		}
+		for _, field := range fields {
+			if v, ok := documentValueAt(doc, field); !ok || v == nil {
+				return errors.Errorf("interstellar: document is missing required field '%s'", field)
+			}
+		}
+		return nil
+	}
+}
+
+// documentValueAt gets the value of the document at the '/'-separated path
+func documentValueAt(doc interface{}, path string) (interface{}, bool) {
+	for _, part := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if doc, ok = obj[part]; !ok {
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+// decodeDocument unmarshals the document, keeping numbers as json.Number so they are not reformatted (such as 1000000 as 1e+06)
+func decodeDocument(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// partitionKeyValue formats a value of a document (from decodeDocument) the way it is given as a partition key value
+// Strings are not quoted; numbers, booleans and null use their JSON representation.
+func partitionKeyValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
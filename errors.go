@@ -16,7 +16,15 @@
 
 package interstellar
 
-import "net/http"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/jet/go-mantis/rest"
+)
 
 // Error is an interstellar generated error
 // This type is an alias for 'string' and is used to ensure the interstellar sential errors can be made constant
@@ -34,7 +42,82 @@ func (e Error) Status() int {
 		return http.StatusNotModified
 	case ErrResourceNotFound:
 		return http.StatusNotFound
+	case ErrResourceConflict:
+		return http.StatusConflict
+	case ErrPayloadTooLarge:
+		return http.StatusRequestEntityTooLarge
 	default:
 		return 0
 	}
 }
+
+// CosmosError is returned for an error response which is not one of the interstellar sentinel errors
+// It captures the details needed to diagnose the error, such as the sub-status code of a 400 Bad Request
+// and the activity id to give to support.
+type CosmosError struct {
+	// StatusCode is the http status code of the response
+	StatusCode int
+	// SubStatus is the x-ms-substatus header of the response, which distinguishes errors with the same status code
+	SubStatus string
+	// ActivityID is the x-ms-activity-id header of the response
+	ActivityID string
+	// Code is the error code in the response body, such as "BadRequest"
+	Code string
+	// Message is the error message in the response body
+	Message string
+	// Body is the raw response body
+	Body []byte
+
+	cause error
+}
+
+// Error implements the error interface for CosmosError
+func (e *CosmosError) Error() string {
+	msg := fmt.Sprintf("interstellar: request failed with status %d", e.StatusCode)
+	if e.SubStatus != "" {
+		msg += fmt.Sprintf(" (substatus %s)", e.SubStatus)
+	}
+	if e.Code != "" {
+		msg += ": " + e.Code
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.ActivityID != "" {
+		msg += fmt.Sprintf(" [activity id %s]", e.ActivityID)
+	}
+	return msg
+}
+
+// Status relays the http status code
+func (e *CosmosError) Status() int {
+	return e.StatusCode
+}
+
+// Cause returns the underlying *rest.ErrorHTTPResponse, for use with errors.Cause
+func (e *CosmosError) Cause() error {
+	return e.cause
+}
+
+// newCosmosError reads and closes the body of the error response, and returns a *CosmosError
+func newCosmosError(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	e := &CosmosError{
+		StatusCode: resp.StatusCode,
+		SubStatus:  resp.Header.Get(HeaderSubStatus),
+		ActivityID: resp.Header.Get(HeaderActivityID),
+		Body:       body,
+		cause:      rest.NewErrorHTTPResponse(resp),
+	}
+	var content struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &content) == nil {
+		e.Code = content.Code
+		e.Message = content.Message
+	}
+	return e
+}
@@ -41,4 +41,12 @@ func TestErrorStatus(t *testing.T) {
 	if hs, ok := err.(hasStatus); !ok || hs.Status() != http.StatusNotFound {
 		t.Fatalf("constant equality check failed")
 	}
+	err = ErrResourceConflict
+	if hs, ok := err.(hasStatus); !ok || hs.Status() != http.StatusConflict {
+		t.Fatalf("constant equality check failed")
+	}
+	err = ErrPayloadTooLarge
+	if hs, ok := err.(hasStatus); !ok || hs.Status() != http.StatusRequestEntityTooLarge {
+		t.Fatalf("constant equality check failed")
+	}
 }
@@ -65,7 +65,7 @@ func TestIntegrationOffers(t *testing.T) {
 
 	// Enumerate offers
 	var offers []interstellar.OfferResource
-	if err := client.ListOffers(ctx, nil, func(resList []interstellar.OfferResource, meta interstellar.ResponseMetadata) (bool, error) {
+	if err := client.ListOffersV2(ctx, nil, func(resList []interstellar.OfferResource, meta interstellar.ResponseMetadata) (bool, error) {
 		offers = append(offers, resList...)
 		return true, nil
 	}); err != nil {
@@ -89,10 +89,6 @@ func TestIntegrationOffers(t *testing.T) {
 		t.Errorf("did not find matching offer for collection %#v", coll)
 		return
 	}
-	if found.Content == nil || found.Content.V2 == nil {
-		t.Errorf("offer content is empty: %s", testutil.ToJSON(found))
-		return
-	}
 	offer, meta, err := client.WithOffer(found.ID).Get(ctx, nil)
 	if err != nil {
 		t.Errorf("offer '%s' could not be retrieved: %v", found.ID, err)
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+
+package testutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/jet/go-interstellar"
+)
+
+// FakeResponse is a canned response served by a FakeRequester
+type FakeResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+}
+
+// Page is a 200 OK response with the body, and a continuation token if there are more pages
+func Page(body string, continuation string) FakeResponse {
+	hdr := make(http.Header)
+	if continuation != "" {
+		hdr.Set(interstellar.HeaderContinuation, continuation)
+	}
+	return FakeResponse{StatusCode: http.StatusOK, Header: hdr, Body: body}
+}
+
+// Throttled is a 429 Too Many Requests response, asking the client to retry after the number of milliseconds
+func Throttled(retryAfterMS int) FakeResponse {
+	hdr := make(http.Header)
+	hdr.Set(interstellar.HeaderRetryAfterMS, strconv.Itoa(retryAfterMS))
+	return FakeResponse{StatusCode: http.StatusTooManyRequests, Header: hdr, Body: `{"code":"TooManyRequests"}`}
+}
+
+// Status is a response with the status code and body, such as a 409 Conflict
+func Status(statusCode int, body string) FakeResponse {
+	return FakeResponse{StatusCode: statusCode, Body: body}
+}
+
+// NotModified is a 304 Not Modified response, as returned by the change feed when there are no further changes
+func NotModified() FakeResponse {
+	return FakeResponse{StatusCode: http.StatusNotModified}
+}
+
+// FakeRequest is a request received by a FakeRequester
+type FakeRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   string
+}
+
+// FakeRequester is an in-memory Requester which serves canned responses by method and path
+// The responses for a method and path are served in order, and the last one is repeated.
+// Requests which have no responses are answered with 404 Not Found.
+//
+//     fake := testutil.NewFakeRequester().
+//         On(http.MethodGet, "/dbs/db1/colls", testutil.Page(page1, "next"), testutil.Page(page2, ""))
+//     client := testutil.NewStubClient(fake)
+//
+type FakeRequester struct {
+	mu        sync.Mutex
+	responses map[string][]FakeResponse
+	requests  []FakeRequest
+}
+
+// NewFakeRequester creates a FakeRequester with no responses
+func NewFakeRequester() *FakeRequester {
+	return &FakeRequester{
+		responses: make(map[string][]FakeResponse),
+	}
+}
+
+func fakeKey(method, path string) string {
+	return method + " " + path
+}
+
+// On adds responses for requests with the method and path
+func (f *FakeRequester) On(method, path string, responses ...FakeResponse) *FakeRequester {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := fakeKey(method, path)
+	f.responses[key] = append(f.responses[key], responses...)
+	return f
+}
+
+// Do records the request and serves the next response for its method and path
+func (f *FakeRequester) Do(req *http.Request) (*http.Response, error) {
+	fr := FakeRequest{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Header: req.Header,
+	}
+	if req.Body != nil {
+		bs, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		fr.Body = string(bs)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, fr)
+	key := fakeKey(req.Method, req.URL.Path)
+	queue := f.responses[key]
+	if len(queue) == 0 {
+		return NewResponse(req, http.StatusNotFound, nil, fmt.Sprintf(`{"code":"NotFound","message":"no response for %s"}`, key)), nil
+	}
+	res := queue[0]
+	if len(queue) > 1 {
+		f.responses[key] = queue[1:]
+	}
+	hdr := make(http.Header, len(res.Header))
+	for k, v := range res.Header {
+		hdr[k] = v
+	}
+	return NewResponse(req, res.StatusCode, hdr, res.Body), nil
+}
+
+// Requests returns the requests received, in order
+func (f *FakeRequester) Requests() []FakeRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeRequest(nil), f.requests...)
+}
+
+// RequestsTo returns the requests received with the method and path, in order
+func (f *FakeRequester) RequestsTo(method, path string) []FakeRequest {
+	var reqs []FakeRequest
+	for _, r := range f.Requests() {
+		if r.Method == method && r.Path == path {
+			reqs = append(reqs, r)
+		}
+	}
+	return reqs
+}
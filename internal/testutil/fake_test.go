@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+
+package testutil_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/jet/go-interstellar"
+	"github.com/jet/go-interstellar/internal/testutil"
+)
+
+func TestFakeRequester(t *testing.T) {
+	fake := testutil.NewFakeRequester().
+		On(http.MethodGet, "/dbs",
+			testutil.Page(`{"Databases":[{"id":"db1"}]}`, "page2"),
+			testutil.Throttled(1),
+			testutil.Page(`{"Databases":[{"id":"db2"}]}`, ""),
+		)
+	client := testutil.NewStubClient(fake)
+	var ids []string
+	err := client.ListDatabases(context.Background(), nil, func(resList []interstellar.DatabaseResource, meta interstellar.ResponseMetadata) (bool, error) {
+		for _, db := range resList {
+			ids = append(ids, db.ID)
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != "db1" || ids[1] != "db2" {
+		t.Errorf("unexpected databases: %v", ids)
+	}
+	reqs := fake.RequestsTo(http.MethodGet, "/dbs")
+	if len(reqs) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(reqs))
+	}
+	for i, cont := range []string{"", "page2", "page2"} {
+		if actual := reqs[i].Header.Get(interstellar.HeaderContinuation); actual != cont {
+			t.Errorf("request %d: expected continuation '%s', got '%s'", i, cont, actual)
+		}
+	}
+
+	_, _, err = client.WithDatabase("missing").Get(context.Background(), nil)
+	if err != interstellar.ErrResourceNotFound {
+		t.Errorf("expected ErrResourceNotFound for a path without responses, got %v", err)
+	}
+	if len(fake.Requests()) != 4 {
+		t.Errorf("expected 4 requests, got %d", len(fake.Requests()))
+	}
+}
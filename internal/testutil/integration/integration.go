@@ -76,8 +76,8 @@ func LoadDatabase(t *testing.T, client *interstellar.Client, path string) func()
 		for _, fn := range cleanup {
 			fn()
 		}
-		ok, meta, err := db.Delete(nil, nil)
-		if err != nil || !ok {
+		_, meta, err := db.DeleteIfExists(nil, nil)
+		if err != nil {
 			t.Errorf("unable to delete db '%s': %v", dbid, err)
 			return
 		}
@@ -129,8 +129,8 @@ func LoadCollection(t *testing.T, client *interstellar.DatabaseClient, path stri
 		LoadDocuments(t, col, filepath.Join(path, "docs.json"))
 	}
 	return func() {
-		ok, meta, err := col.Delete(nil, nil)
-		if err != nil || !ok {
+		_, meta, err := col.DeleteIfExists(nil, nil)
+		if err != nil {
 			t.Errorf("unable to delete collection '%s': %v", req.ID, err)
 		}
 		testutil.DebugLog(t, "Collection Deleted. Metadata:\n%s", testutil.ToJSON(meta))
@@ -42,10 +42,33 @@ func NewTestLoggingRequester(t *testing.T, r interstellar.Requester) interstella
 // Do runs the request and logs the request and response dumps to the Test logger
 func (r TestLoggingRequester) Do(req *http.Request) (*http.Response, error) {
 	r.T.Helper()
-	debugreq, _ := httputil.DumpRequest(req, true)
+	debugreq, _ := DumpRedactedRequest(req)
 	r.T.Logf("HTTP REQUEST\n%s", string(debugreq))
 	resp, err := r.Requester.Do(req)
 	debugres, _ := httputil.DumpResponse(resp, true)
 	r.T.Logf("HTTP RESPONSE\n%s", string(debugres))
 	return resp, err
 }
+
+// RedactedHeaders are the request headers which are redacted by DumpRedactedRequest
+// The Authorization token (and the date it was signed with) can be replayed as a credential.
+var RedactedHeaders = []string{interstellar.HeaderAuthorization, interstellar.HeaderMSDate}
+
+// DumpRedactedRequest dumps the request like httputil.DumpRequest (including the body),
+// except the values of the RedactedHeaders are replaced with interstellar.RedactedValue
+func DumpRedactedRequest(req *http.Request) ([]byte, error) {
+	dreq := *req
+	dreq.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		dreq.Header[k] = v
+	}
+	for _, h := range RedactedHeaders {
+		if dreq.Header.Get(h) != "" {
+			dreq.Header.Set(h, interstellar.RedactedValue)
+		}
+	}
+	dump, err := httputil.DumpRequest(&dreq, true)
+	// DumpRequest replaces the body it reads with an equivalent one
+	req.Body = dreq.Body
+	return dump, err
+}
@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package testutil_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jet/go-interstellar"
+	"github.com/jet/go-interstellar/internal/testutil"
+)
+
+func TestDumpRedactedRequest(t *testing.T) {
+	client := testutil.NewStubClient(nil)
+	client.Authorizer = interstellar.MasterKey("not-a-real-key")
+	req, err := client.NewHTTPRequest(nil, interstellar.ClientRequest{
+		Method:       http.MethodPost,
+		Path:         "/dbs/db1/colls/col1/docs",
+		ResourceType: interstellar.ResourceDocuments,
+		ResourceLink: "dbs/db1/colls/col1",
+		Body:         strings.NewReader(`{"id":"1"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := req.Header.Get(interstellar.HeaderAuthorization)
+	date := req.Header.Get(interstellar.HeaderMSDate)
+	if token == "" || date == "" {
+		t.Fatal("expected the request to be authorized")
+	}
+	dump, err := testutil.DumpRedactedRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(dump), token) || strings.Contains(string(dump), date) {
+		t.Errorf("expected the authorization token to be redacted:\n%s", dump)
+	}
+	if !strings.Contains(string(dump), `{"id":"1"}`) {
+		t.Errorf("expected the body to be dumped:\n%s", dump)
+	}
+	if req.Header.Get(interstellar.HeaderAuthorization) != token {
+		t.Errorf("expected the request headers to be unchanged")
+	}
+	body, _ := ioutil.ReadAll(req.Body)
+	if string(body) != `{"id":"1"}` {
+		t.Errorf("expected the request body to be readable after the dump, got '%s'", body)
+	}
+}
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package testutil
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/jet/go-interstellar"
+)
+
+// RequesterFunc implements interstellar.Requester for a pure function
+// Can be used to stub out the API with an anonymous function such as RequesterFunc(func(req *http.Request) (*http.Response, error) { ... })
+type RequesterFunc func(req *http.Request) (*http.Response, error)
+
+// Do implementation for the interstellar.Requester interface
+func (fn RequesterFunc) Do(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+// NewResponse creates an *http.Response to the given request with the status code, headers, and body
+func NewResponse(req *http.Request, status int, header http.Header, body string) *http.Response {
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewBufferString(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// NewStubClient creates an *interstellar.Client which sends all of its requests to the given Requester
+// The client uses the TestKey authorizer, so no real signing is performed
+func NewStubClient(r interstellar.Requester) *interstellar.Client {
+	return &interstellar.Client{
+		UserAgent:  "Test/1.0",
+		Endpoint:   "https://localhost:8081",
+		Authorizer: TestKey("TESTING"),
+		Requester:  r,
+	}
+}
@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+
+package interstellar
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// requestLimiter is a semaphore shared by a Client and its copies, which bounds the number of requests in flight
+type requestLimiter struct {
+	max int
+	sem chan struct{}
+}
+
+// WithMaxConcurrentRequests creates a copy of the client which has at most max requests in flight at once, or no limit if max is not positive
+// The limit is shared by the copies made of the returned client (such as with WithEndpoint), but not with the original client.
+// This bounds the parallelism of an application which shares one client, without every caller throttling itself:
+//
+//     client = client.WithMaxConcurrentRequests(32)
+//
+// When the limit is reached, each operation waits for an earlier request to finish, or fails with the error of its context.
+// A request is finished when its response body has been read and closed, so pagination callbacks do not hold a request.
+func (c *Client) WithMaxConcurrentRequests(max int) *Client {
+	cc := *c
+	cc.limiter = nil
+	if max > 0 {
+		cc.limiter = &requestLimiter{max: max, sem: make(chan struct{}, max)}
+	}
+	return &cc
+}
+
+// MaxConcurrentRequests returns the number of requests the client may have in flight at once, or zero if it is not limited
+// See WithMaxConcurrentRequests
+func (c *Client) MaxConcurrentRequests() int {
+	if c.limiter == nil {
+		return 0
+	}
+	return c.limiter.max
+}
+
+// acquireRequest waits for one of the Client's MaxConcurrentRequests to be available, or for the context to be done
+// The returned function releases the request, and may be called more than once.
+func (c *Client) acquireRequest(ctx context.Context) (func(), error) {
+	l := c.limiter
+	if l == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-l.sem })
+	}, nil
+}
+
+// releasingBody releases the request when the response body is closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package interstellar
+
+import (
+	"net/http"
+	"time"
+)
+
+// Logger receives a summary of each request sent by the Client
+// The summary never includes the request or response bodies (such as documents), nor the Authorization header.
+type Logger interface {
+	LogRequest(entry RequestLogEntry)
+}
+
+// LoggerFunc implements Logger for a pure function
+type LoggerFunc func(entry RequestLogEntry)
+
+// LogRequest implementation for the Logger interface
+func (fn LoggerFunc) LogRequest(entry RequestLogEntry) {
+	fn(entry)
+}
+
+// RequestLogEntry is the summary of a request and its response given to a Logger
+type RequestLogEntry struct {
+	// Method is the HTTP method of the request
+	Method string
+	// Path is the URL path of the request
+	Path string
+	// ResourceType is the type of resource being requested
+	ResourceType ResourceType
+	// ResourceLink is the resource link of the request
+	ResourceLink string
+	// Header are the request headers
+	// The Authorization header is always redacted, since it can be replayed as a credential.
+	Header http.Header
+	// StatusCode is the HTTP status code of the response, or zero if there was no response
+	StatusCode int
+	// RequestCharge is the request units (RU) charged for the operation
+	RequestCharge string
+	// ActivityID is the activity id of the response, which can be given to Azure support
+	ActivityID string
+	// Latency is the time taken to receive the response, including any retries by the Requester
+	Latency time.Duration
+	// Err is the error returned by the Requester, if any
+	Err error
+}
+
+// RedactedValue replaces the values of sensitive headers in a RequestLogEntry
+const RedactedValue = "REDACTED"
+
+// doLogged sends the request with the Requester, and logs a summary of the exchange if the Client has a Logger
+func (c *Client) doLogged(req *http.Request, request ClientRequest) (*http.Response, error) {
+	if c.Logger == nil {
+		return c.Requester.Do(req)
+	}
+	start := time.Now()
+	resp, err := c.Requester.Do(req)
+	entry := RequestLogEntry{
+		Method:       req.Method,
+		Path:         req.URL.Path,
+		ResourceType: request.ResourceType,
+		ResourceLink: request.ResourceLink,
+		Header:       redactHeader(req.Header),
+		Latency:      time.Since(start),
+		Err:          err,
+	}
+	if resp != nil {
+		entry.StatusCode = resp.StatusCode
+		entry.RequestCharge = resp.Header.Get(HeaderRequestCharge)
+		entry.ActivityID = resp.Header.Get(HeaderActivityID)
+	}
+	c.Logger.LogRequest(entry)
+	return resp, err
+}
+
+func redactHeader(hdr http.Header) http.Header {
+	redacted := make(http.Header, len(hdr))
+	for k, v := range hdr {
+		redacted[k] = append([]string(nil), v...)
+	}
+	if _, ok := redacted[HeaderAuthorization]; ok {
+		redacted.Set(HeaderAuthorization, RedactedValue)
+	}
+	return redacted
+}
@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package interstellar_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/jet/go-interstellar"
+	"github.com/jet/go-interstellar/internal/testutil"
+)
+
+func TestClientLogger(t *testing.T) {
+	hdr := make(http.Header)
+	hdr.Set(interstellar.HeaderRequestCharge, "1.23")
+	hdr.Set(interstellar.HeaderActivityID, "a0a0a0a0-0000-0000-0000-000000000000")
+	fake := testutil.NewFakeRequester().
+		On(http.MethodGet, "/dbs/db1/colls/col1/docs/1", testutil.FakeResponse{StatusCode: http.StatusOK, Header: hdr, Body: `{"id":"1","secret":"s3cr3t"}`})
+	client := testutil.NewStubClient(fake)
+	var entries []interstellar.RequestLogEntry
+	client.Logger = interstellar.LoggerFunc(func(entry interstellar.RequestLogEntry) {
+		entries = append(entries, entry)
+	})
+	var doc interstellar.DocumentProperties
+	if _, err := client.WithDatabase("db1").WithCollection("col1").WithDocument("1", nil).Get(context.Background(), nil, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 log entry, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.Method != http.MethodGet || entry.ResourceLink != "dbs/db1/colls/col1/docs/1" || entry.ResourceType != interstellar.ResourceDocuments {
+		t.Errorf("unexpected request in log entry: %s", testutil.ToJSON(entry))
+	}
+	if entry.StatusCode != http.StatusOK || entry.RequestCharge != "1.23" || entry.ActivityID != "a0a0a0a0-0000-0000-0000-000000000000" {
+		t.Errorf("unexpected response in log entry: %s", testutil.ToJSON(entry))
+	}
+	authorization := fake.Requests()[0].Header.Get(interstellar.HeaderAuthorization)
+	if authorization == "" || authorization == interstellar.RedactedValue {
+		t.Fatalf("expected the request to be sent with the real Authorization header, got '%s'", authorization)
+	}
+	if auth := entry.Header.Get(interstellar.HeaderAuthorization); auth != interstellar.RedactedValue {
+		t.Errorf("expected the Authorization header to be redacted, got '%s'", auth)
+	}
+}
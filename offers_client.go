@@ -22,8 +22,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"net/url"
-	"strings"
+	"time"
+
+	"github.com/pkg/errors"
 )
 
 // ListOffersRaw lists each offer in the CosmosDB account as raw JSON objects
@@ -55,6 +56,40 @@ func (c *Client) QueryOffersRaw(ctx context.Context, query *Query, fn PaginateRa
 	}, fn)
 }
 
+// OfferForDatabase gets the offer of a database with shared throughput, whose OfferResourceID is the database's _rid
+// ErrResourceNotFound is returned if the database does not have an offer, such as when only its collections have provisioned throughput.
+func (c *Client) OfferForDatabase(ctx context.Context, db *DatabaseResource) (*OfferResource, error) {
+	if db == nil || db.ResourceID == "" {
+		return nil, Error("interstellar: database must have a resource id to find its offer")
+	}
+	return c.offerForResource(ctx, db.ResourceID)
+}
+
+// offerForResource queries for the offer whose OfferResourceID is the given _rid
+func (c *Client) offerForResource(ctx context.Context, rid string) (*OfferResource, error) {
+	query := &Query{Query: "SELECT * FROM root r WHERE r.offerResourceId = @rid"}
+	query.AddParameter("@rid", rid)
+	var found *OfferResource
+	err := c.QueryOffersRaw(ctx, query, func(resList []json.RawMessage, meta ResponseMetadata) (bool, error) {
+		if len(resList) == 0 {
+			return true, nil
+		}
+		var offer OfferResource
+		if err := json.Unmarshal(resList[0], &offer); err != nil {
+			return false, err
+		}
+		found = &offer
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrResourceNotFound
+	}
+	return found, nil
+}
+
 // PaginateOfferResource pagination function for a list of OfferResource
 type PaginateOfferResource func(resList []OfferResource, meta ResponseMetadata) (bool, error)
 
@@ -73,13 +108,143 @@ func (c *Client) ListOffers(ctx context.Context, opts RequestOptions, fn Paginat
 	})
 }
 
+// AllOffers lists every offer in the CosmosDB account
+// This is a convenience for tooling which adjusts the throughput of many offers; see OfferResource.WithThroughput
+//
+//     offers, err := client.AllOffers(ctx)
+//     for _, offer := range offers {
+//         if offer.Content != nil && offer.Content.V2 != nil && offer.Content.V2.OfferThroughput > 400 {
+//             _, _, err = client.ReplaceOffer(ctx, interstellar.ReplaceOfferRequest{Offer: offer.WithThroughput(400)})
+//         }
+//     }
+//
+func (c *Client) AllOffers(ctx context.Context) ([]OfferResource, error) {
+	var offers []OfferResource
+	err := c.ListOffers(ctx, nil, func(resList []OfferResource, meta ResponseMetadata) (bool, error) {
+		offers = append(offers, resList...)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return offers, nil
+}
+
+// ListOffersV2 lists each offer in the CosmosDB account which uses the V2 (user-defined throughput) schema
+// V1 offers are skipped, so every OfferResource given to the pagination function has a non-nil Content.V2
+// Pages which only contained V1 offers are passed to the pagination function as empty lists.
+func (c *Client) ListOffersV2(ctx context.Context, opts RequestOptions, fn PaginateOfferResource) error {
+	return c.ListOffers(ctx, opts, func(resList []OfferResource, meta ResponseMetadata) (bool, error) {
+		offers := make([]OfferResource, 0, len(resList))
+		for _, offer := range resList {
+			if offer.Content != nil && offer.Content.V2 != nil {
+				offers = append(offers, offer)
+			}
+		}
+		return fn(offers, meta)
+	})
+}
+
+// TotalProvisionedThroughput lists the V2 offers in the account and sums their provisioned throughput (RU/s)
+// The breakdown of the throughput of each offer is keyed by the OfferResourceID (the _rid of the database or collection).
+// V1 offers are skipped, since they use pre-defined performance levels instead of provisioned throughput.
+func (c *Client) TotalProvisionedThroughput(ctx context.Context) (int, map[string]int, error) {
+	total := 0
+	byResource := make(map[string]int)
+	err := c.ListOffersV2(ctx, nil, func(resList []OfferResource, meta ResponseMetadata) (bool, error) {
+		for _, offer := range resList {
+			total += offer.Content.V2.OfferThroughput
+			byResource[offer.OfferResourceID] += offer.Content.V2.OfferThroughput
+		}
+		return true, nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return total, byResource, nil
+}
+
+// ThroughputInfo is the provisioned throughput of a collection, or a database with shared throughput
+type ThroughputInfo struct {
+	// DatabaseID is the ID of the database
+	DatabaseID string
+	// CollectionID is the ID of the collection, or empty for throughput shared by the collections in the database
+	CollectionID string
+	// Throughput is the provisioned throughput (RU/s)
+	Throughput int
+	// AutoscaleMaxThroughput is the maximum throughput (RU/s) of an autoscale offer, or zero when autoscale is not enabled
+	AutoscaleMaxThroughput int
+}
+
+// CollectionThroughputReport lists the V2 offers in the account, and resolves each offer to its database and collection ID
+// The databases and collections are listed to map the OfferResourceID of each offer to the resource it belongs to.
+// Offers which cannot be resolved (such as for a resource deleted in the meantime) are skipped.
+func (c *Client) CollectionThroughputReport(ctx context.Context) ([]ThroughputInfo, error) {
+	resources := make(map[string]ThroughputInfo)
+	err := c.ListDatabases(ctx, nil, func(dbs []DatabaseResource, meta ResponseMetadata) (bool, error) {
+		for _, db := range dbs {
+			resources[db.ResourceID] = ThroughputInfo{DatabaseID: db.ID}
+			err := c.WithDatabase(db.ID).ListCollections(ctx, nil, func(colls []CollectionResource, meta ResponseMetadata) (bool, error) {
+				for _, coll := range colls {
+					resources[coll.ResourceID] = ThroughputInfo{DatabaseID: db.ID, CollectionID: coll.ID}
+				}
+				return true, nil
+			})
+			if err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var report []ThroughputInfo
+	err = c.ListOffersV2(ctx, nil, func(offers []OfferResource, meta ResponseMetadata) (bool, error) {
+		for _, offer := range offers {
+			info, ok := resources[offer.OfferResourceID]
+			if !ok {
+				continue
+			}
+			info.Throughput = offer.Content.V2.OfferThroughput
+			if offer.Content.V2.AutopilotSettings != nil {
+				info.AutoscaleMaxThroughput = offer.Content.V2.AutopilotSettings.MaxThroughput
+			}
+			report = append(report, info)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
 // OfferClient is a client scoped to a single offer
 // Used to perform API calls within the scope of the Offer resource
 type OfferClient struct {
 	Client  *Client
 	OfferID string
+
+	// PollInterval is the time to wait between polls in WaitForThroughput
+	// If not set, DefaultThroughputPollInterval is used
+	PollInterval time.Duration
+
+	// SkipThroughputValidation disables the client-side ValidateThroughput check in SetThroughput
+	// The throughput is then sent as-is, and the server is left to reject invalid values.
+	SkipThroughputValidation bool
+
+	// UpdateRetries is the number of times Update re-reads the offer and tries again when the replace fails with ErrPreconditionFailed
+	// If not set, DefaultOfferUpdateRetries is used
+	UpdateRetries int
 }
 
+// DefaultOfferUpdateRetries is the default number of times OfferClient.Update retries after the offer was changed concurrently
+const DefaultOfferUpdateRetries = 3
+
+// DefaultThroughputPollInterval is the default time to wait between polls of the offer in OfferClient.WaitForThroughput
+const DefaultThroughputPollInterval = 5 * time.Second
+
 // WithOffer creates a OfferClient for the given Offer within this account
 func (c *Client) WithOffer(id string) *OfferClient {
 	return &OfferClient{
@@ -92,7 +257,7 @@ func (c *Client) WithOffer(id string) *OfferClient {
 func (c *OfferClient) GetRaw(ctx context.Context, opts RequestOptions) ([]byte, *ResponseMetadata, error) {
 	return c.Client.GetResource(ctx, ClientRequest{
 		Path:         fmt.Sprintf("/offers/%s", c.OfferID),
-		ResourceLink: strings.ToLower(c.OfferID),
+		ResourceLink: OfferResourceLink(c.OfferID),
 		ResourceType: ResourceOffers,
 		Options:      opts,
 	})
@@ -111,6 +276,109 @@ func (c *OfferClient) Get(ctx context.Context, opts RequestOptions) (*OfferResou
 	return &offer, meta, nil
 }
 
+// WaitForThroughput polls the offer until its provisioned throughput equals the targetRU, and no replace is pending.
+// After replacing an offer, the new throughput may take some time to be applied.
+// Polling stops when the context is cancelled or expires, in which case the context's error is returned along with the last offer read.
+func (c *OfferClient) WaitForThroughput(ctx context.Context, targetRU int) (*OfferResource, *ResponseMetadata, error) {
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = DefaultThroughputPollInterval
+	}
+	for {
+		offer, meta, err := c.Get(ctx, nil)
+		if err != nil {
+			return nil, meta, err
+		}
+		if offer.Content != nil && offer.Content.V2 != nil && offer.Content.V2.OfferThroughput == targetRU && !meta.OfferReplacePending {
+			return offer, meta, nil
+		}
+		select {
+		case <-ctx.Done():
+			return offer, meta, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+const (
+	// MinOfferThroughput is the minimum throughput (RU/s) which can be provisioned with a V2 offer
+	MinOfferThroughput = 400
+	// OfferThroughputIncrement is the increment (RU/s) in which throughput must be provisioned with a V2 offer
+	OfferThroughputIncrement = 100
+)
+
+// ValidateThroughput checks that the throughput is at least MinOfferThroughput, and a multiple of OfferThroughputIncrement
+// This only rejects values which are obviously invalid; the server is the final authority on the throughput which may be provisioned,
+// since the limits depend on the account and the amount of data stored.
+func ValidateThroughput(throughput int) error {
+	if throughput < MinOfferThroughput {
+		return errors.Errorf("interstellar: invalid throughput %d: must be at least %d RU/s", throughput, MinOfferThroughput)
+	}
+	if throughput%OfferThroughputIncrement != 0 {
+		return errors.Errorf("interstellar: invalid throughput %d: must be a multiple of %d RU/s", throughput, OfferThroughputIncrement)
+	}
+	return nil
+}
+
+// SetThroughput replaces the offer with a V2 offer that has the given throughput (RU/s)
+// The throughput is checked with ValidateThroughput before the offer is read, unless SkipThroughputValidation is set.
+// The offer is replaced with Update, so a concurrent change to the offer is retried.
+// Note: The new throughput may not be applied immediately; see WaitForThroughput.
+func (c *OfferClient) SetThroughput(ctx context.Context, throughput int, opts RequestOptions) (*OfferResource, *ResponseMetadata, error) {
+	if !c.SkipThroughputValidation {
+		if err := ValidateThroughput(throughput); err != nil {
+			return nil, nil, err
+		}
+	}
+	return c.update(ctx, func(content *OfferContentV2) {
+		content.OfferThroughput = throughput
+	}, opts)
+}
+
+// Update reads the offer, applies the mutation to its V2 content, and replaces the offer with If-Match set to the ETag that was read.
+// If the offer was changed concurrently, the replace fails with ErrPreconditionFailed; the offer is then read again
+// and the mutation re-applied, up to UpdateRetries times, after which ErrPreconditionFailed is returned.
+// Since it may be called more than once, the mutation must only depend on the content it is given.
+//
+// The offer is always replaced as a V2 offer. For example:
+//
+//     offer, _, err := oc.Update(ctx, func(content *interstellar.OfferContentV2) {
+//         content.OfferThroughput += 1000
+//     })
+//
+func (c *OfferClient) Update(ctx context.Context, mutate func(*OfferContentV2)) (*OfferResource, *ResponseMetadata, error) {
+	return c.update(ctx, mutate, nil)
+}
+
+func (c *OfferClient) update(ctx context.Context, mutate func(*OfferContentV2), opts RequestOptions) (*OfferResource, *ResponseMetadata, error) {
+	retries := c.UpdateRetries
+	if retries <= 0 {
+		retries = DefaultOfferUpdateRetries
+	}
+	for attempt := 0; ; attempt++ {
+		offer, meta, err := c.Get(ctx, nil)
+		if err != nil {
+			return nil, meta, err
+		}
+		offer.OfferVersion = OfferV2
+		offer.OfferType = OfferTypeInvalid
+		if offer.Content == nil {
+			offer.Content = &OfferContent{}
+		}
+		if offer.Content.V2 == nil {
+			offer.Content.V2 = &OfferContentV2{}
+		}
+		mutate(offer.Content.V2)
+		offer, meta, err = c.Client.ReplaceOffer(ctx, ReplaceOfferRequest{
+			Offer:   offer,
+			Options: mergeOptions(opts, &CommonRequestOptions{IfMatch: offer.ETag}),
+		})
+		if err != ErrPreconditionFailed || attempt >= retries {
+			return offer, meta, err
+		}
+	}
+}
+
 // ReplaceOfferRequest encapsulates the offer to replace
 type ReplaceOfferRequest struct {
 	Offer   *OfferResource
@@ -126,7 +394,7 @@ func (r ReplaceOfferRequest) ApplyOptions(req *http.Request) {
 
 // ReplaceOffer replaces an existing offer with new parameters
 func (c *Client) ReplaceOffer(ctx context.Context, req ReplaceOfferRequest) (*OfferResource, *ResponseMetadata, error) {
-	rl := fmt.Sprintf("offers/%s", url.PathEscape(req.Offer.ResourceID))
+	rl := BuildResourceLink("offers", req.Offer.ResourceID)
 	body, err := req.Offer.MarshalJSON()
 	if err != nil {
 		return nil, nil, err
@@ -134,7 +402,7 @@ func (c *Client) ReplaceOffer(ctx context.Context, req ReplaceOfferRequest) (*Of
 	resp, meta, err := c.CreateOrReplaceResource(ctx, ClientRequest{
 		Method:       http.MethodPut,
 		Path:         fmt.Sprintf("/%s", rl),
-		ResourceLink: strings.ToLower(req.Offer.ResourceID),
+		ResourceLink: OfferResourceLink(req.Offer.ResourceID),
 		ResourceType: ResourceOffers,
 		Body:         bytes.NewBuffer(body),
 		Options:      req,
@@ -148,3 +416,11 @@ func (c *Client) ReplaceOffer(ctx context.Context, req ReplaceOfferRequest) (*Of
 	}
 	return &result, meta, err
 }
+
+// WithOptions creates a copy of the OfferClient which applies the options to every request made by it
+// See Client.WithOptions
+func (c *OfferClient) WithOptions(opts RequestOptions) *OfferClient {
+	cc := *c
+	cc.Client = c.Client.WithOptions(opts)
+	return &cc
+}
@@ -0,0 +1,303 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package interstellar_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jet/go-interstellar"
+	"github.com/jet/go-interstellar/internal/testutil"
+)
+
+const offersPage = `{"Offers":[
+	{"id":"v2","_rid":"v2","offerVersion":"V2","offerType":"Invalid","content":{"offerThroughput":500},"resource":"dbs/yEcCAA==/colls/yEcCAPX6aAw=/","offerResourceId":"yEcCAPX6aAw="},
+	{"id":"v1","_rid":"v1","offerVersion":"V1","offerType":"S1","resource":"dbs/yEcCAA==/colls/PaYSAPH7qAo=/","offerResourceId":"PaYSAPH7qAo="}
+]}`
+
+func TestListOffersV2(t *testing.T) {
+	client := testutil.NewStubClient(testutil.NewFakeRequester().On(http.MethodGet, "/offers", testutil.Page(offersPage, "")))
+	var offers []interstellar.OfferResource
+	if err := client.ListOffersV2(context.Background(), nil, func(resList []interstellar.OfferResource, meta interstellar.ResponseMetadata) (bool, error) {
+		offers = append(offers, resList...)
+		return true, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(offers) != 1 || offers[0].ID != "v2" {
+		t.Fatalf("expected only the V2 offer, got %s", testutil.ToJSON(offers))
+	}
+	if offers[0].Content.V2.OfferThroughput != 500 {
+		t.Errorf("expected throughput 500, got %d", offers[0].Content.V2.OfferThroughput)
+	}
+}
+
+func TestTotalProvisionedThroughput(t *testing.T) {
+	client := testutil.NewStubClient(testutil.NewFakeRequester().On(http.MethodGet, "/offers",
+		testutil.Page(offersPage, "page-2"),
+		testutil.Page(`{"Offers":[{"id":"v2b","_rid":"v2b","offerVersion":"V2","offerType":"Invalid","content":{"offerThroughput":1000},"resource":"dbs/yEcCAA==/","offerResourceId":"yEcCAA=="}]}`, "")))
+	total, byResource, err := client.TotalProvisionedThroughput(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1500 {
+		t.Errorf("expected total throughput 1500, got %d", total)
+	}
+	expected := map[string]int{"yEcCAPX6aAw=": 500, "yEcCAA==": 1000}
+	if len(byResource) != len(expected) || byResource["yEcCAPX6aAw="] != 500 || byResource["yEcCAA=="] != 1000 {
+		t.Errorf("expected breakdown %v, got %v", expected, byResource)
+	}
+}
+
+func TestCollectionThroughputReport(t *testing.T) {
+	client := testutil.NewStubClient(testutil.NewFakeRequester().
+		On(http.MethodGet, "/dbs", testutil.Page(`{"Databases":[{"id":"db1","_rid":"yEcCAA=="},{"id":"db2","_rid":"zFdDAA=="}]}`, "")).
+		On(http.MethodGet, "/dbs/db1/colls", testutil.Page(`{"DocumentCollections":[{"id":"col1","_rid":"yEcCAPX6aAw="},{"id":"col2","_rid":"PaYSAPH7qAo="}]}`, "")).
+		On(http.MethodGet, "/dbs/db2/colls", testutil.Page(`{"DocumentCollections":[]}`, "")).
+		On(http.MethodGet, "/offers", testutil.Page(`{"Offers":[
+			{"id":"a","offerVersion":"V2","offerType":"Invalid","content":{"offerThroughput":500},"offerResourceId":"yEcCAPX6aAw="},
+			{"id":"b","offerVersion":"V1","offerType":"S1","offerResourceId":"PaYSAPH7qAo="},
+			{"id":"c","offerVersion":"V2","offerType":"Invalid","content":{"offerThroughput":400,"offerAutopilotSettings":{"maxThroughput":4000}},"offerResourceId":"zFdDAA=="},
+			{"id":"d","offerVersion":"V2","offerType":"Invalid","content":{"offerThroughput":400},"offerResourceId":"deleted"}
+		]}`, "")))
+	report, err := client.CollectionThroughputReport(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []interstellar.ThroughputInfo{
+		{DatabaseID: "db1", CollectionID: "col1", Throughput: 500},
+		{DatabaseID: "db2", Throughput: 400, AutoscaleMaxThroughput: 4000},
+	}
+	if len(report) != len(expected) {
+		t.Fatalf("expected %d entries, got %s", len(expected), testutil.ToJSON(report))
+	}
+	for i := range expected {
+		if report[i] != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], report[i])
+		}
+	}
+}
+
+func TestOfferWaitForThroughput(t *testing.T) {
+	offer := func(throughput int) string {
+		return fmt.Sprintf(`{"id":"Hu+t","_rid":"Hu+t","offerVersion":"V2","offerType":"Invalid","content":{"offerThroughput":%d}}`, throughput)
+	}
+	pending := make(http.Header)
+	pending.Set(interstellar.HeaderOfferReplacePending, "true")
+	fake := testutil.NewFakeRequester().On(http.MethodGet, "/offers/Hu+t",
+		// not yet applied
+		testutil.Page(offer(400), ""),
+		testutil.FakeResponse{StatusCode: http.StatusOK, Header: pending, Body: offer(1000)},
+		testutil.Page(offer(1000), ""))
+	client := testutil.NewStubClient(fake)
+	oc := client.WithOffer("Hu+t")
+	oc.PollInterval = time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, _, err := oc.WaitForThroughput(ctx, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if polls := len(fake.Requests()); polls != 3 || result.Content.V2.OfferThroughput != 1000 {
+		t.Errorf("expected throughput of 1000 after 3 polls, got %d after %d polls", result.Content.V2.OfferThroughput, polls)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err = oc.WaitForThroughput(ctx, 2000); err != context.DeadlineExceeded {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestValidateThroughput(t *testing.T) {
+	for throughput, valid := range map[int]bool{
+		400:   true,
+		1000:  true,
+		25000: true,
+		0:     false,
+		300:   false,
+		450:   false,
+		-400:  false,
+	} {
+		if err := interstellar.ValidateThroughput(throughput); (err == nil) != valid {
+			t.Errorf("throughput %d: expected valid=%v, got %v", throughput, valid, err)
+		}
+	}
+}
+
+func TestOfferSetThroughput(t *testing.T) {
+	var replaced string
+	client := testutil.NewStubClient(testutil.RequesterFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.Method {
+		case http.MethodGet:
+			return testutil.NewResponse(req, http.StatusOK, nil, `{"id":"Hu+t","_rid":"Hu+t","offerVersion":"V2","offerType":"Invalid","content":{"offerThroughput":400}}`), nil
+		case http.MethodPut:
+			body, _ := ioutil.ReadAll(req.Body)
+			replaced = string(body)
+			return testutil.NewResponse(req, http.StatusOK, nil, replaced), nil
+		}
+		t.Fatalf("unexpected request %s %s", req.Method, req.URL.Path)
+		return nil, nil
+	}))
+	oc := client.WithOffer("Hu+t")
+	if _, _, err := oc.SetThroughput(context.Background(), 450, nil); err == nil {
+		t.Fatal("expected a validation error")
+	}
+	if replaced != "" {
+		t.Fatal("expected invalid throughput not to be sent")
+	}
+	offer, _, err := oc.SetThroughput(context.Background(), 1000, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offer.Content.V2.OfferThroughput != 1000 {
+		t.Errorf("expected throughput 1000, got %s", replaced)
+	}
+	oc.SkipThroughputValidation = true
+	if offer, _, err = oc.SetThroughput(context.Background(), 450, nil); err != nil {
+		t.Fatal(err)
+	}
+	if offer.Content.V2.OfferThroughput != 450 {
+		t.Errorf("expected throughput 450, got %s", replaced)
+	}
+}
+
+func TestOfferUpdate(t *testing.T) {
+	gets := 0
+	var ifMatch []string
+	client := testutil.NewStubClient(testutil.RequesterFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.Method {
+		case http.MethodGet:
+			gets++
+			return testutil.NewResponse(req, http.StatusOK, nil, fmt.Sprintf(`{"id":"Hu+t","_rid":"Hu+t","_etag":"etag%d","offerVersion":"V2","content":{"offerThroughput":%d}}`, gets, 400+gets*100)), nil
+		case http.MethodPut:
+			ifMatch = append(ifMatch, req.Header.Get(interstellar.HeaderIfMatch))
+			if len(ifMatch) == 1 {
+				// changed concurrently by someone else
+				return testutil.NewResponse(req, http.StatusPreconditionFailed, nil, `{"code":"PreconditionFailed"}`), nil
+			}
+			body, _ := ioutil.ReadAll(req.Body)
+			return testutil.NewResponse(req, http.StatusOK, nil, string(body)), nil
+		}
+		t.Fatalf("unexpected request %s %s", req.Method, req.URL.Path)
+		return nil, nil
+	}))
+	oc := client.WithOffer("Hu+t")
+	offer, _, err := oc.Update(context.Background(), func(content *interstellar.OfferContentV2) {
+		content.OfferThroughput += 1000
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offer.Content.V2.OfferThroughput != 1600 {
+		t.Errorf("expected the mutation to be applied to the re-read offer, got %d", offer.Content.V2.OfferThroughput)
+	}
+	if len(ifMatch) != 2 || ifMatch[0] != "etag1" || ifMatch[1] != "etag2" {
+		t.Errorf("unexpected If-Match headers: %v", ifMatch)
+	}
+
+	oc.UpdateRetries = 1
+	ifMatch = nil
+	client.Requester = testutil.RequesterFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPut {
+			ifMatch = append(ifMatch, req.Header.Get(interstellar.HeaderIfMatch))
+			return testutil.NewResponse(req, http.StatusPreconditionFailed, nil, `{"code":"PreconditionFailed"}`), nil
+		}
+		return testutil.NewResponse(req, http.StatusOK, nil, `{"id":"Hu+t","_rid":"Hu+t","_etag":"etag","offerVersion":"V2","content":{"offerThroughput":400}}`), nil
+	})
+	if _, _, err = oc.SetThroughput(context.Background(), 1000, nil); err != interstellar.ErrPreconditionFailed {
+		t.Errorf("expected ErrPreconditionFailed, got %v", err)
+	}
+	if len(ifMatch) != 2 {
+		t.Errorf("expected 2 attempts, got %d", len(ifMatch))
+	}
+}
+
+func TestOfferForDatabase(t *testing.T) {
+	client := testutil.NewStubClient(testutil.RequesterFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodPost || req.URL.Path != "/offers" {
+			t.Fatalf("unexpected request %s %s", req.Method, req.URL.Path)
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		var query interstellar.Query
+		if err := json.Unmarshal(body, &query); err != nil {
+			t.Fatal(err)
+		}
+		if len(query.Parameters) == 1 && query.Parameters[0].Value == "yEcCAA==" {
+			return testutil.NewResponse(req, http.StatusOK, nil, `{"Offers":[{"id":"db","_rid":"db","offerVersion":"V2","content":{"offerThroughput":1000},"offerResourceId":"yEcCAA=="}]}`), nil
+		}
+		return testutil.NewResponse(req, http.StatusOK, nil, `{"Offers":[]}`), nil
+	}))
+	offer, err := client.OfferForDatabase(context.Background(), &interstellar.DatabaseResource{ID: "db1", ResourceID: "yEcCAA=="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offer.ID != "db" || offer.Content.V2.OfferThroughput != 1000 {
+		t.Errorf("unexpected offer: %s", testutil.ToJSON(offer))
+	}
+	if _, err = client.OfferForDatabase(context.Background(), &interstellar.DatabaseResource{ID: "db2", ResourceID: "PaYSAA=="}); err != interstellar.ErrResourceNotFound {
+		t.Errorf("expected ErrResourceNotFound, got %v", err)
+	}
+}
+
+func TestAllOffersWithThroughput(t *testing.T) {
+	client := testutil.NewStubClient(testutil.NewFakeRequester().On(http.MethodGet, "/offers", testutil.Page(offersPage, "")))
+	offers, err := client.AllOffers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offers) != 2 {
+		t.Fatalf("expected both offers, got %s", testutil.ToJSON(offers))
+	}
+	for _, offer := range offers {
+		scaled := offer.WithThroughput(1000)
+		if scaled.OfferVersion != interstellar.OfferV2 || scaled.OfferType != interstellar.OfferTypeInvalid {
+			t.Errorf("expected a V2 offer, got %s %s", scaled.OfferVersion, scaled.OfferType)
+		}
+		if scaled.Content.V2.OfferThroughput != 1000 || scaled.ID != offer.ID || scaled.ETag != offer.ETag {
+			t.Errorf("unexpected offer %s", testutil.ToJSON(scaled))
+		}
+		if offer.Content != nil && offer.Content.V2 != nil && offer.Content.V2.OfferThroughput == 1000 {
+			t.Error("expected the original offer to be unchanged")
+		}
+	}
+}
+
+func TestReplaceOfferIfMatch(t *testing.T) {
+	fake := testutil.NewFakeRequester().On(http.MethodPut, "/offers/v1", testutil.Page(`{"id":"v1","_rid":"v1"}`, ""))
+	client := testutil.NewStubClient(fake)
+	offer := &interstellar.OfferResource{ID: "v1", ResourceID: "v1", ETag: `"1"`, OfferVersion: interstellar.OfferV1, OfferType: "S1"}
+	scaled := offer.WithThroughput(1000)
+	for _, opts := range []interstellar.RequestOptions{nil, &interstellar.CommonRequestOptions{IfMatch: `"2"`}} {
+		if _, _, err := client.ReplaceOffer(context.Background(), interstellar.ReplaceOfferRequest{Offer: scaled, Options: opts}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var ifMatch []string
+	for _, req := range fake.Requests() {
+		ifMatch = append(ifMatch, req.Header.Get(interstellar.HeaderIfMatch))
+	}
+	// the replace is only conditional when IfMatch is requested
+	if len(ifMatch) != 2 || ifMatch[0] != "" || ifMatch[1] != `"2"` {
+		t.Errorf("unexpected If-Match headers: %q", ifMatch)
+	}
+}
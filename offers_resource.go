@@ -19,6 +19,8 @@ package interstellar
 import (
 	"encoding/json"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 // OfferResource represents a performance-level offering on a resource
@@ -48,12 +50,57 @@ type offerJSON struct {
 	OfferResourceID string          `json:"offerResourceId"`
 }
 
+// WithThroughput returns a copy of the offer with the provisioned throughput (RU/s), ready to be replaced with Client.ReplaceOffer
+// The copy is a V2 (user-defined throughput) offer; the offer itself is not modified.
+// The ETag is kept, so the replacement fails with ErrPreconditionFailed if the offer was changed after it was read (when sent with IfMatch).
+// The autoscale settings of the offer are kept; change AutopilotSettings.MaxThroughput to scale an autoscale offer.
+func (oc *OfferResource) WithThroughput(ru int) *OfferResource {
+	offer := *oc
+	offer.OfferVersion = OfferV2
+	offer.OfferType = OfferTypeInvalid
+	content := OfferContentV2{}
+	if oc.Content != nil && oc.Content.V2 != nil {
+		content = *oc.Content.V2
+	}
+	content.OfferThroughput = ru
+	offer.Content = &OfferContent{V2: &content}
+	return &offer
+}
+
 // OfferContentV2 is the content of the OfferVersion "V2" for user-defined throughput
 type OfferContentV2 struct {
 	OfferThroughput int `json:"offerThroughput"`
 
 	// RUPMEnabled is Request Units(RU)/Minute throughput is enabled/disabled for collection in the Azure Cosmos DB service.
 	RUPMEnabled *bool `json:"offerIsRUPerMinuteThroughputEnabled,omitempty"`
+
+	// AutopilotSettings are set when the offer uses autoscale throughput
+	AutopilotSettings *OfferAutopilotSettings `json:"offerAutopilotSettings,omitempty"`
+}
+
+// OfferContentV1 is the content of the OfferVersion "V1" for the pre-defined performance levels
+// V1 offers have no content in the response body; the performance level is the OfferType of the offer.
+type OfferContentV1 struct {
+	OfferType OfferType
+}
+
+// RequestUnits is the throughput (RU/s) of the pre-defined performance level, or 0 if the level is unknown
+func (c *OfferContentV1) RequestUnits() int {
+	switch c.OfferType {
+	case OfferTypeS1:
+		return 250
+	case OfferTypeS2:
+		return 1000
+	case OfferTypeS3:
+		return 2500
+	}
+	return 0
+}
+
+// OfferAutopilotSettings are the autoscale settings of a V2 offer
+type OfferAutopilotSettings struct {
+	// MaxThroughput is the maximum throughput (RU/s) the offer will scale up to
+	MaxThroughput int `json:"maxThroughput"`
 }
 
 // OfferVersion differentiates different offer schemas
@@ -82,6 +129,8 @@ const (
 
 // OfferContent encapsulates the different offer schemas depending on OfferVersion
 type OfferContent struct {
+	// V1 is the OfferContentV1 version of the schema for pre-defined performance levels
+	V1 *OfferContentV1
 	// V2 is the OfferContentV2 version of the schema for user-defined performance parameters
 	V2 *OfferContentV2
 }
@@ -110,6 +159,10 @@ func (oc *OfferResource) UnmarshalJSON(data []byte) error {
 			return err
 		}
 		oc.Content = &content
+	} else if oc.OfferVersion == OfferV1 {
+		oc.Content = &OfferContent{
+			V1: &OfferContentV1{OfferType: oc.OfferType},
+		}
 	}
 	return nil
 }
@@ -136,3 +189,31 @@ func (oc *OfferResource) MarshalJSON() ([]byte, error) {
 	}
 	return json.Marshal(&offerjs)
 }
+
+// HeaderOfferReplacePending is returned when reading an offer, and is "true" if a throughput change has not finished being applied.
+const HeaderOfferReplacePending = "x-ms-offer-replace-pending"
+
+// HeaderPhysicalPartitionThroughputInfo is returned when reading throughput and describes the throughput of each physical partition.
+// The value is a JSON array of objects with the physical partition ID and its throughput; see ParsePhysicalPartitionThroughputInfo.
+const HeaderPhysicalPartitionThroughputInfo = "x-ms-cosmos-physical-partition-throughput-info"
+
+// PhysicalPartitionThroughput is the throughput allotted to a single physical partition
+type PhysicalPartitionThroughput struct {
+	// ID is the identifier of the physical partition
+	ID string `json:"id"`
+	// Throughput is the provisioned RU/s of the physical partition
+	Throughput float64 `json:"throughput"`
+}
+
+// ParsePhysicalPartitionThroughputInfo parses the value of the x-ms-cosmos-physical-partition-throughput-info header
+// This is useful for diagnosing partitions with skewed throughput. An empty value returns a nil list.
+func ParsePhysicalPartitionThroughputInfo(value string) ([]PhysicalPartitionThroughput, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var info []PhysicalPartitionThroughput
+	if err := json.Unmarshal([]byte(value), &info); err != nil {
+		return nil, errors.Wrap(err, "interstellar: could not parse physical partition throughput info")
+	}
+	return info, nil
+}
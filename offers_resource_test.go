@@ -19,6 +19,7 @@ package interstellar_test
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"path/filepath"
 	"testing"
 
@@ -59,3 +60,46 @@ func TestOfferResourceMarshallJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePhysicalPartitionThroughputInfo(t *testing.T) {
+	resp := &http.Response{Header: make(http.Header)}
+	resp.Header.Set(interstellar.HeaderPhysicalPartitionThroughputInfo, `[{"id":"0","throughput":1200},{"id":"1","throughput":400.5}]`)
+	meta := interstellar.GetResponseMetadata(resp)
+	info, err := interstellar.ParsePhysicalPartitionThroughputInfo(meta.PhysicalPartitionThroughputInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []interstellar.PhysicalPartitionThroughput{
+		{ID: "0", Throughput: 1200},
+		{ID: "1", Throughput: 400.5},
+	}
+	if diff := deep.Equal(expected, info); diff != nil {
+		t.Fatal(diff)
+	}
+	if info, err = interstellar.ParsePhysicalPartitionThroughputInfo(""); err != nil || info != nil {
+		t.Errorf("expected empty header to parse to nil, got %v %v", info, err)
+	}
+	if _, err = interstellar.ParsePhysicalPartitionThroughputInfo("not json"); err == nil {
+		t.Errorf("expected malformed header to fail")
+	}
+}
+
+func TestOfferResourceV1Content(t *testing.T) {
+	var offer interstellar.OfferResource
+	data := `{"id":"Vm1t","offerVersion":"V1","offerType":"S2","resource":"dbs/PaYSAA==/colls/PaYSAIxUPws=/","offerResourceId":"PaYSAIxUPws="}`
+	if err := json.Unmarshal([]byte(data), &offer); err != nil {
+		t.Fatal(err)
+	}
+	if offer.Content == nil || offer.Content.V1 == nil {
+		t.Fatal("expected V1 content")
+	}
+	if offer.Content.V2 != nil {
+		t.Error("expected no V2 content")
+	}
+	if offer.Content.V1.OfferType != interstellar.OfferTypeS2 {
+		t.Errorf("expected offer type S2, got %s", offer.Content.V1.OfferType)
+	}
+	if ru := offer.Content.V1.RequestUnits(); ru != 1000 {
+		t.Errorf("expected 1000 RU/s, got %d", ru)
+	}
+}
@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package interstellar
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math/bits"
+	"strings"
+)
+
+// HashPartitionKey computes the effective partition key of a string partition key value, the same way Cosmos DB does
+// for collections with the V2 (MurmurHash3) hash partitioning scheme.
+// The result is an upper-case hex string which can be compared with the MinInclusive and MaxExclusive of each
+// PartitionKeyRangeResource, to find which partition key range (physical partition) the value is stored in (see PartitionKeyRangeResource.Contains).
+//
+// Collections created with the original (V1) partitioning scheme, or with hierarchical partition keys, are hashed differently.
+func HashPartitionKey(value string) string {
+	// the value is encoded with a type marker (0x08 for strings) and terminated with 0xFF
+	data := make([]byte, 0, len(value)+2)
+	data = append(data, 0x08)
+	data = append(data, value...)
+	data = append(data, 0xFF)
+	h1, h2 := murmurHash3x64128(data, 0)
+	// the 128-bit hash is written big-endian, with the top two bits cleared so it is always less than the "FF" max
+	var hash [16]byte
+	binary.BigEndian.PutUint64(hash[:8], h2)
+	binary.BigEndian.PutUint64(hash[8:], h1)
+	hash[0] &= 0x3F
+	return strings.ToUpper(hex.EncodeToString(hash[:]))
+}
+
+// Contains reports whether the effective partition key (such as from HashPartitionKey) is in the range
+func (r PartitionKeyRangeResource) Contains(epk string) bool {
+	return r.MinInclusive <= epk && (r.MaxExclusive == "FF" || epk < r.MaxExclusive)
+}
+
+// murmurHash3x64128 is the x64 128-bit variant of MurmurHash3
+func murmurHash3x64128(data []byte, seed uint64) (uint64, uint64) {
+	const (
+		c1 = 0x87c37b91114253d5
+		c2 = 0x4cf5ad432745937f
+	)
+	h1, h2 := seed, seed
+	n := len(data)
+	for len(data) >= 16 {
+		k1 := binary.LittleEndian.Uint64(data[:8])
+		k2 := binary.LittleEndian.Uint64(data[8:16])
+		data = data[16:]
+
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+		h1 = bits.RotateLeft64(h1, 27)
+		h1 += h2
+		h1 = h1*5 + 0x52dce729
+
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+		h2 = bits.RotateLeft64(h2, 31)
+		h2 += h1
+		h2 = h2*5 + 0x38495ab5
+	}
+	var k1, k2 uint64
+	for i := len(data) - 1; i >= 8; i-- {
+		k2 = k2<<8 | uint64(data[i])
+	}
+	if len(data) > 8 {
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+	}
+	tail := len(data)
+	if tail > 8 {
+		tail = 8
+	}
+	for i := tail - 1; i >= 0; i-- {
+		k1 = k1<<8 | uint64(data[i])
+	}
+	if len(data) > 0 {
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+	}
+	h1 ^= uint64(n)
+	h2 ^= uint64(n)
+	h1 += h2
+	h2 += h1
+	h1 = fmix64(h1)
+	h2 = fmix64(h2)
+	h1 += h2
+	h2 += h1
+	return h1, h2
+}
+
+func fmix64(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package interstellar
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestMurmurHash3x64128(t *testing.T) {
+	cases := map[string]string{
+		"":             "00000000000000000000000000000000",
+		"hello":        "cbd8a7b341bd9b025b1e906a48ae1d19",
+		"hello, world": "342fac623a5ebc8e4cdcbc079642414d",
+		"The quick brown fox jumps over the lazy dog": "e34bbc7bbc071b6c7a433ca9c49a9347",
+	}
+	for in, want := range cases {
+		h1, h2 := murmurHash3x64128([]byte(in), 0)
+		if got := fmt.Sprintf("%016x%016x", h1, h2); got != want {
+			t.Errorf("murmurHash3x64128(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+// The effective partition keys computed by the service, from the Azure Cosmos DB SDKs' V2 hash tests
+func TestHashPartitionKeyKnownValues(t *testing.T) {
+	cases := map[string]string{
+		"":                        "32E9366E637A71B4E710384B2F4970A0",
+		"partitionKey":            "013AEFCF77FA271571CF665A58C933F1",
+		strings.Repeat("a", 1024): "332BDF5512AE49615F32C7D98C2DB86C",
+	}
+	for in, want := range cases {
+		if got := HashPartitionKey(in); got != want {
+			t.Errorf("HashPartitionKey(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestHashPartitionKey(t *testing.T) {
+	format := regexp.MustCompile(`^[0-3][0-9A-F]{31}$`)
+	for _, v := range []string{"", "a", "partition-key", "ü"} {
+		epk := HashPartitionKey(v)
+		if !format.MatchString(epk) {
+			t.Errorf("HashPartitionKey(%q) = %s, not a valid effective partition key", v, epk)
+		}
+		if epk != HashPartitionKey(v) {
+			t.Errorf("HashPartitionKey(%q) is not stable", v)
+		}
+	}
+	if HashPartitionKey("a") == HashPartitionKey("b") {
+		t.Errorf("expected different values to hash differently")
+	}
+
+	ranges := []PartitionKeyRangeResource{
+		{ID: "0", MinInclusive: "", MaxExclusive: "1F"},
+		{ID: "1", MinInclusive: "1F", MaxExclusive: "FF"},
+	}
+	epk := HashPartitionKey("partition-key")
+	var found []string
+	for _, r := range ranges {
+		if r.Contains(epk) {
+			found = append(found, r.ID)
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("expected %s to be in exactly one range, got %v", epk, found)
+	}
+}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package interstellar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// PartitionKeyRangeResource represents a range of effective partition key values which are served by one physical partition of a collection
+// See https://docs.microsoft.com/en-us/rest/api/cosmos-db/get-partition-key-ranges for the latest documentation
+type PartitionKeyRangeResource struct {
+	// ID is the identifier of the partition key range, which is used with the x-ms-documentdb-partitionkeyrangeid header.
+	ID string `json:"id"`
+	// ResourceID is a unique identifier that is also hierarchical per the resource stack on the resource model.
+	ResourceID string `json:"_rid,omitempty"`
+	// Timestamp is a system generated property. It denotes the last updated timestamp of the resource.
+	Timestamp int64 `json:"_ts,omitempty"`
+	// Self is the unique addressable URI for the resource.
+	Self string `json:"_self,omitempty"`
+	// ETag value required for optimistic concurrency control.
+	ETag string `json:"_etag,omitempty"`
+	// MinInclusive is the minimum effective partition key hash value of the range (inclusive)
+	MinInclusive string `json:"minInclusive"`
+	// MaxExclusive is the maximum effective partition key hash value of the range (exclusive)
+	MaxExclusive string `json:"maxExclusive"`
+	// RIDPrefix is the resource id prefix of the range
+	RIDPrefix int `json:"ridPrefix,omitempty"`
+	// ThroughputFraction is the fraction of the collection's provisioned throughput allotted to this range
+	ThroughputFraction float64 `json:"throughputFraction,omitempty"`
+	// Status of the range, such as "online"
+	Status string `json:"status,omitempty"`
+	// Parents lists the IDs of the ranges this range was split from
+	Parents []string `json:"parents,omitempty"`
+}
+
+// ListPartitionKeyRangesRaw lists each partition key range in the collection as raw JSON objects
+func (c *CollectionClient) ListPartitionKeyRangesRaw(ctx context.Context, opts RequestOptions, fn PaginateRawResources) error {
+	rl := c.ResourceLink()
+	return c.Client.ListResources(ctx, "PartitionKeyRanges", ClientRequest{
+		Path:         fmt.Sprintf("/%s/pkranges", rl),
+		ResourceLink: rl,
+		ResourceType: ResourcePartitionKeyRanges,
+		Options:      opts,
+	}, fn)
+}
+
+// PaginatePartitionKeyRangeResource pagination function for a list of PartitionKeyRangeResource
+type PaginatePartitionKeyRangeResource func(resList []PartitionKeyRangeResource, meta ResponseMetadata) (bool, error)
+
+// ListPartitionKeyRanges lists each partition key range in the collection
+func (c *CollectionClient) ListPartitionKeyRanges(ctx context.Context, opts RequestOptions, fn PaginatePartitionKeyRangeResource) error {
+	return c.ListPartitionKeyRangesRaw(ctx, opts, func(resList []json.RawMessage, meta ResponseMetadata) (bool, error) {
+		ranges := make([]PartitionKeyRangeResource, len(resList))
+		for i, res := range resList {
+			var pkr PartitionKeyRangeResource
+			if err := json.Unmarshal(res, &pkr); err != nil {
+				return false, err
+			}
+			ranges[i] = pkr
+		}
+		return fn(ranges, meta)
+	})
+}
@@ -18,8 +18,12 @@ package interstellar
 
 import (
 	"bytes"
+	"encoding/base64"
 	"fmt"
 	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
 )
 
 // Query encapsulates a SQL-like query on the  Collection
@@ -44,8 +48,27 @@ type Query struct {
 	// EnableCrossPartition enables the query to span across multiple partitions.
 	EnableCrossPartition bool `json:"-"`
 
-	// ConsistencytLevel sets the consistency level override.
+	// ContinuationExpected sets the x-ms-documentdb-query-iscontinuationexpected header, if not nil.
+	// When true, the gateway may return a partial page with a continuation token (such as after draining one partition of a cross-partition query),
+	// and the results are only complete once every continuation has been followed.
+	// When false, the gateway is asked to return the results without a continuation; this is only suitable for queries that fit in a single page.
+	// This mostly matters when EnableCrossPartition is set, since single-partition queries are served from one partition.
+	ContinuationExpected *bool `json:"-"`
+
+	// EnableScan allows the query to scan documents when no suitable index exists, such as for a one-off query on an excluded path.
+	// Scans are expensive, so this should not be used for regular queries.
+	EnableScan bool `json:"-"`
+
+	// ContinuationTokenLimitKB limits the size of the continuation tokens returned by the query, if greater than zero.
+	// Continuation tokens of cross-partition queries can grow large; this keeps them small enough to persist, such as for resumable jobs.
+	// The gateway may return fewer results per page to stay within the limit.
+	ContinuationTokenLimitKB int `json:"-"`
+
+	// ConsistencyLevel sets the consistency level override.
 	// This must be the same or weaker than the account's configured consistency level.
+	ConsistencyLevel ConsistencyLevel `json:"-"`
+
+	// Deprecated: ConsistencytLevel is misspelled; use ConsistencyLevel, which takes precedence if both are set.
 	ConsistencytLevel ConsistencyLevel `json:"-"`
 
 	// SessionToken must be set when using a consistency level of "Session".
@@ -95,6 +118,32 @@ func (q *Query) String() string {
 	return buf.String()
 }
 
+// DebugString returns the String representation of the Query, followed by the options which affect the request
+// such as MaxItemCount, EnableCrossPartition and the consistency level.
+// Only the presence of the continuation and session tokens is shown, since they are opaque (and may be large).
+// This is not used in the API, it is only useful for debugging; such as diagnosing pagination issues.
+func (q *Query) DebugString() string {
+	if q == nil {
+		return ""
+	}
+	buf := bytes.NewBufferString(q.String())
+	fmt.Fprintf(buf, " {MaxItemCount: %d, EnableCrossPartition: %t", q.MaxItemCount, q.EnableCrossPartition)
+	if level := consistencyOverride(q.ConsistencyLevel, q.ConsistencytLevel); level != "" {
+		fmt.Fprintf(buf, ", ConsistencyLevel: %s", level)
+	}
+	if q.ContinuationExpected != nil {
+		fmt.Fprintf(buf, ", ContinuationExpected: %t", *q.ContinuationExpected)
+	}
+	if q.EnableScan {
+		buf.WriteString(", EnableScan: true")
+	}
+	if q.ContinuationTokenLimitKB > 0 {
+		fmt.Fprintf(buf, ", ContinuationTokenLimitKB: %d", q.ContinuationTokenLimitKB)
+	}
+	fmt.Fprintf(buf, ", Continuation: %t, SessionToken: %t}", q.Continuation != "", q.SessionToken != "")
+	return buf.String()
+}
+
 // QueryParameter encapsulates a named query parameter for a  query along with its value
 type QueryParameter struct {
 	// Name is the name of the query parameter.
@@ -126,12 +175,21 @@ func (q *Query) ApplyOptions(req *http.Request) {
 	if q.SessionToken != "" {
 		req.Header.Set(HeaderSessionToken, q.SessionToken)
 	}
-	if q.ConsistencytLevel != "" {
-		req.Header.Set(HeaderConsistencyLevel, string(q.ConsistencytLevel))
+	if level := consistencyOverride(q.ConsistencyLevel, q.ConsistencytLevel); level != "" {
+		req.Header.Set(HeaderConsistencyLevel, string(level))
 	}
 	if q.EnableCrossPartition {
 		req.Header.Set(HeaderDocDBQueryEnableCrossPartition, "true")
 	}
+	if q.ContinuationExpected != nil {
+		req.Header.Set(HeaderDocDBQueryIsContinuationExpected, strconv.FormatBool(*q.ContinuationExpected))
+	}
+	if q.EnableScan {
+		req.Header.Set(HeaderDocDBQueryEnableScan, "true")
+	}
+	if q.ContinuationTokenLimitKB > 0 {
+		req.Header.Set(HeaderDocDBResponseContinuationTokenLimitKB, strconv.Itoa(q.ContinuationTokenLimitKB))
+	}
 	if q.Continuation != "" {
 		req.Header.Set(HeaderContinuation, q.Continuation)
 	}
@@ -139,3 +197,95 @@ func (q *Query) ApplyOptions(req *http.Request) {
 		req.Header.Set(HeaderMaxItemCount, fmt.Sprintf("%d", q.MaxItemCount))
 	}
 }
+
+// QueryBuilder builds a parameterized Query from query text with '?' placeholders
+// Each placeholder is replaced with a generated parameter name (@p0, @p1, ...) which is bound to the corresponding value:
+//
+//     query, err := interstellar.NewQueryBuilder("SELECT * FROM c WHERE c.lastName = ? AND c.age > ?").Bind("Andersen", 21).Build()
+//
+// Placeholders inside string literals are ignored, as is the '??' (coalesce) operator.
+// The '?' of the ternary operator cannot be distinguished from a placeholder, so use a Query with named parameters for queries which need it.
+type QueryBuilder struct {
+	text   string
+	values []interface{}
+}
+
+// NewQueryBuilder creates a QueryBuilder for the query text
+func NewQueryBuilder(text string) *QueryBuilder {
+	return &QueryBuilder{text: text}
+}
+
+// Bind adds values for the next placeholders, in order
+func (b *QueryBuilder) Bind(values ...interface{}) *QueryBuilder {
+	b.values = append(b.values, values...)
+	return b
+}
+
+// Build creates the Query
+// An error is returned if the number of placeholders does not match the number of bound values.
+func (b *QueryBuilder) Build() (*Query, error) {
+	q := &Query{}
+	buf := &bytes.Buffer{}
+	var quote rune
+	n := 0
+	runes := []rune(b.text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0:
+			if r == '\\' && i+1 < len(runes) {
+				// escaped character within a string literal
+				buf.WriteRune(r)
+				i++
+				r = runes[i]
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '?' && i+1 < len(runes) && runes[i+1] == '?':
+			// coalesce operator
+			buf.WriteString("??")
+			i++
+			continue
+		case r == '?':
+			if n >= len(b.values) {
+				return nil, errors.Errorf("interstellar: query has more placeholders than the %d bound value(s)", len(b.values))
+			}
+			name := fmt.Sprintf("@p%d", n)
+			q.AddParameter(name, b.values[n])
+			buf.WriteString(name)
+			n++
+			continue
+		}
+		buf.WriteRune(r)
+	}
+	if n != len(b.values) {
+		return nil, errors.Errorf("interstellar: query has %d placeholder(s), but %d value(s) were bound", n, len(b.values))
+	}
+	q.Query = buf.String()
+	return q, nil
+}
+
+// EncodeQueryCursor encodes a continuation token into an opaque cursor string which is safe to use in a URL
+// An empty continuation token (the last page) encodes to an empty cursor.
+func EncodeQueryCursor(continuation string) string {
+	if continuation == "" {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(continuation))
+}
+
+// DecodeQueryCursor decodes a cursor created by EncodeQueryCursor back into the continuation token
+// The result can be set as the Continuation on the Query to get the next page of results.
+// An empty cursor decodes to an empty continuation token (the first page).
+func DecodeQueryCursor(cursor string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	cont, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", Error("interstellar: invalid query cursor")
+	}
+	return string(cont), nil
+}
@@ -0,0 +1,222 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+
+package interstellar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Aggregate is how QueryGrouped merges a property of the rows of the same group
+type Aggregate string
+
+const (
+	// AggregateSum adds the values together, which merges SUM aggregates
+	AggregateSum = Aggregate("SUM")
+	// AggregateCount adds the values together, which merges COUNT aggregates
+	AggregateCount = Aggregate("COUNT")
+	// AggregateMin keeps the least value, which merges MIN aggregates of numbers or strings
+	AggregateMin = Aggregate("MIN")
+	// AggregateMax keeps the greatest value, which merges MAX aggregates of numbers or strings
+	AggregateMax = Aggregate("MAX")
+)
+
+// QueryGrouped runs a DISTINCT or GROUP BY query, and finalizes its results on the client by merging the rows with the same group key
+// The gateway runs cross-partition DISTINCT and GROUP BY queries on each partition separately, so the same group
+// can be returned more than once (once for each partition, on different pages) and must be combined by the client.
+//
+// Each key path is a property of the result rows (such as "city", or "address.city" for a nested property) which identifies the group.
+// The aggregates name the properties of the rows which are merged, and how:
+//
+//     query := &interstellar.Query{
+//         Query:                "SELECT c.city, COUNT(1) AS orders, MAX(c.total) AS largest FROM c GROUP BY c.city",
+//         EnableCrossPartition: true,
+//     }
+//     rows, err := cc.QueryGrouped(ctx, query, []string{"city"}, map[string]interstellar.Aggregate{
+//         "orders":  interstellar.AggregateCount,
+//         "largest": interstellar.AggregateMax,
+//     })
+//
+// Any other property keeps its value from the first row of the group. An AVG cannot be merged from the averages of each partition;
+// select a SUM and a COUNT, and divide them instead.
+// With no key paths, each whole row is the key, so duplicate rows (such as from SELECT DISTINCT) are removed.
+// Rows are returned in the order their group was first seen.
+func (c *CollectionClient) QueryGrouped(ctx context.Context, query *Query, keyPaths []string, aggregates map[string]Aggregate) ([]json.RawMessage, error) {
+	var groups []interface{}
+	index := make(map[string]int)
+	err := c.queryDocumentsRaw(ctx, query, nil, func(resList []json.RawMessage, meta ResponseMetadata) (bool, error) {
+		for _, raw := range resList {
+			row, err := decodeGroupRow(raw)
+			if err != nil {
+				return false, err
+			}
+			key, err := groupKey(row, keyPaths)
+			if err != nil {
+				return false, err
+			}
+			if i, ok := index[key]; ok {
+				mergeGroupRow(groups[i], row, aggregates)
+				continue
+			}
+			index[key] = len(groups)
+			groups = append(groups, row)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	results := make([]json.RawMessage, len(groups))
+	for i, g := range groups {
+		data, err := json.Marshal(g)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = data
+	}
+	return results, nil
+}
+
+// mergeGroupRow merges the aggregated properties of the row into the first row of its group
+func mergeGroupRow(group, row interface{}, aggregates map[string]Aggregate) {
+	dst, ok := group.(map[string]interface{})
+	if !ok {
+		return
+	}
+	src, ok := row.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for name, agg := range aggregates {
+		v, ok := src[name]
+		if !ok {
+			continue
+		}
+		cur, ok := dst[name]
+		if !ok {
+			// such as a partition without any values for MIN or MAX
+			dst[name] = v
+			continue
+		}
+		switch agg {
+		case AggregateSum, AggregateCount:
+			x, okx := cur.(json.Number)
+			y, oky := v.(json.Number)
+			if okx && oky {
+				dst[name] = addNumbers(x, y)
+			}
+		case AggregateMin, AggregateMax:
+			if cmp, ok := compareValues(v, cur); ok && ((agg == AggregateMin && cmp < 0) || (agg == AggregateMax && cmp > 0)) {
+				dst[name] = v
+			}
+		}
+	}
+}
+
+// addNumbers adds two JSON numbers, as integers when both are integers
+func addNumbers(a, b json.Number) json.Number {
+	if x, err := a.Int64(); err == nil {
+		if y, err := b.Int64(); err == nil {
+			return json.Number(strconv.FormatInt(x+y, 10))
+		}
+	}
+	x, _ := a.Float64()
+	y, _ := b.Float64()
+	return json.Number(strconv.FormatFloat(x+y, 'g', -1, 64))
+}
+
+// compareValues compares two numbers or two strings; it is false for any other values
+func compareValues(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return 0, false
+		}
+		if i, err := x.Int64(); err == nil {
+			if j, err := y.Int64(); err == nil {
+				return compareInt64(i, j), true
+			}
+		}
+		f, _ := x.Float64()
+		g, _ := y.Float64()
+		switch {
+		case f < g:
+			return -1, true
+		case f > g:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(x, y), true
+	}
+	return 0, false
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func decodeGroupRow(raw json.RawMessage) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var row interface{}
+	if err := dec.Decode(&row); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// groupKey returns the canonical JSON of the values at the key paths of the row (or of the whole row, if there are no key paths)
+func groupKey(row interface{}, keyPaths []string) (string, error) {
+	if len(keyPaths) == 0 {
+		data, err := json.Marshal(row)
+		return string(data), err
+	}
+	values := make([]interface{}, len(keyPaths))
+	for i, p := range keyPaths {
+		values[i] = lookupPath(row, p)
+	}
+	data, err := json.Marshal(values)
+	return string(data), err
+}
+
+// lookupPath gets the value of a dotted property path, or nil if it is missing
+func lookupPath(v interface{}, path string) interface{} {
+	for _, name := range strings.Split(path, ".") {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = obj[name]
+	}
+	return v
+}
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+
+package interstellar_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jet/go-interstellar"
+	"github.com/jet/go-interstellar/internal/testutil"
+)
+
+func TestQueryGrouped(t *testing.T) {
+	fake := testutil.NewFakeRequester().
+		On(http.MethodPost, "/dbs/db1/colls/col1/docs",
+			testutil.Page(`{"Documents":[{"city":"Hoboken","zip":7030,"orders":2,"total":10.5,"largest":8,"smallest":2.5},{"city":"Seattle","zip":98101,"orders":1,"total":3,"largest":3,"smallest":3}]}`, "page2"),
+			testutil.Page(`{"Documents":[{"city":"Hoboken","zip":7030,"orders":3,"total":4.25,"largest":2,"smallest":1},{"orders":1,"total":1,"largest":1,"smallest":1}]}`, ""),
+		)
+	coll := testutil.NewStubClient(fake).WithDatabase("db1").WithCollection("col1")
+	query := &interstellar.Query{
+		Query:                "SELECT c.city, c.zip, COUNT(1) AS orders, SUM(c.total) AS total, MAX(c.total) AS largest, MIN(c.total) AS smallest FROM c GROUP BY c.city, c.zip",
+		EnableCrossPartition: true,
+	}
+	rows, err := coll.QueryGrouped(context.Background(), query, []string{"city"}, map[string]interstellar.Aggregate{
+		"orders":   interstellar.AggregateCount,
+		"total":    interstellar.AggregateSum,
+		"largest":  interstellar.AggregateMax,
+		"smallest": interstellar.AggregateMin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`{"city":"Hoboken","largest":8,"orders":5,"smallest":1,"total":14.75,"zip":7030}`,
+		`{"city":"Seattle","largest":3,"orders":1,"smallest":3,"total":3,"zip":98101}`,
+		`{"largest":1,"orders":1,"smallest":1,"total":1}`,
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("expected %d groups, got %d: %s", len(expected), len(rows), testutil.ToJSON(rows))
+	}
+	for i, row := range rows {
+		if string(row) != expected[i] {
+			t.Errorf("group %d: expected %s, got %s", i, expected[i], row)
+		}
+	}
+}
+
+func TestQueryGroupedDistinct(t *testing.T) {
+	fake := testutil.NewFakeRequester().
+		On(http.MethodPost, "/dbs/db1/colls/col1/docs",
+			testutil.Page(`{"Documents":["Hoboken","Seattle"]}`, "page2"),
+			testutil.Page(`{"Documents":["Seattle","Austin"]}`, ""),
+		)
+	coll := testutil.NewStubClient(fake).WithDatabase("db1").WithCollection("col1")
+	rows, err := coll.QueryGrouped(context.Background(), &interstellar.Query{Query: "SELECT DISTINCT VALUE c.city FROM c"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cities []string
+	for _, row := range rows {
+		var city string
+		if err := json.Unmarshal(row, &city); err != nil {
+			t.Fatal(err)
+		}
+		cities = append(cities, city)
+	}
+	if strings.Join(cities, ",") != "Hoboken,Seattle,Austin" {
+		t.Errorf("unexpected distinct values: %v", cities)
+	}
+}
@@ -18,6 +18,7 @@ package interstellar_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -105,6 +106,25 @@ func TestQueryFormatter(t *testing.T) {
 	}
 }
 
+func TestQueryDebugString(t *testing.T) {
+	query := &interstellar.Query{
+		Query:                "SELECT * FROM c WHERE c.email = @email",
+		MaxItemCount:         10,
+		EnableCrossPartition: true,
+		ConsistencyLevel:     interstellar.ConsistencyEventual,
+		Continuation:         `{"token":"+RID:YrMqAKFnpn5IAAAAAAAAAA==#RT:3","range":{"min":"","max":"FF"}}`,
+	}
+	query.AddParameterSensitive("@email", "john.doe@example.com")
+	expected := `SELECT * FROM c WHERE c.email = @email; [@email: !(sensitive)] {MaxItemCount: 10, EnableCrossPartition: true, ConsistencyLevel: Eventual, Continuation: true, SessionToken: false}`
+	if actual := query.DebugString(); actual != expected {
+		t.Fatalf("expected=%s\nactual=%s", expected, actual)
+	}
+	var nilQuery *interstellar.Query
+	if nilQuery.DebugString() != "" {
+		t.Fatalf("expected nil query to be empty")
+	}
+}
+
 func TestQueryRequestOptions(t *testing.T) {
 	expectedFile := filepath.Join("./testdata", "query", "expected-request.txt")
 	expected := testutil.ReadFileBytes(t, expectedFile)
@@ -145,3 +165,222 @@ func TestQueryRequestOptions(t *testing.T) {
 		t.Fatalf("expected query request does not equal actual. Compare %s with %s", expectedFile, actualFile)
 	}
 }
+
+func TestQueryContinuationExpected(t *testing.T) {
+	client := testutil.NewStubClient(nil)
+	expected := false
+	for _, value := range []*bool{nil, &expected} {
+		query := &interstellar.Query{
+			Query:                "SELECT * FROM c",
+			EnableCrossPartition: true,
+			ContinuationExpected: value,
+		}
+		req, err := client.NewHTTPRequest(nil, interstellar.ClientRequest{
+			Method:       http.MethodPost,
+			Path:         "/dbs/db1/colls/col1/docs",
+			ResourceType: interstellar.ResourceDocuments,
+			Options:      query,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		hdr, ok := req.Header[http.CanonicalHeaderKey(interstellar.HeaderDocDBQueryIsContinuationExpected)]
+		if value == nil && ok {
+			t.Errorf("expected no header when ContinuationExpected is nil, got %v", hdr)
+		}
+		if value != nil && (len(hdr) != 1 || hdr[0] != "false") {
+			t.Errorf("expected header to be 'false', got %v", hdr)
+		}
+	}
+}
+
+func TestQueryBuilder(t *testing.T) {
+	examples := []struct {
+		name     string
+		text     string
+		values   []interface{}
+		expected string
+		err      bool
+	}{
+		{
+			name:     "placeholders",
+			text:     "SELECT * FROM c WHERE c.lastName = ? AND c.age > ?",
+			values:   []interface{}{"Andersen", 21},
+			expected: "SELECT * FROM c WHERE c.lastName = @p0 AND c.age > @p1",
+		},
+		{
+			name:     "literals",
+			text:     `SELECT * FROM c WHERE c.a = '?' AND c.b = "it\'s ?" AND c.c = ?`,
+			values:   []interface{}{1},
+			expected: `SELECT * FROM c WHERE c.a = '?' AND c.b = "it\'s ?" AND c.c = @p0`,
+		},
+		{
+			name:     "coalesce",
+			text:     "SELECT c.a ?? ? AS a FROM c",
+			values:   []interface{}{"default"},
+			expected: "SELECT c.a ?? @p0 AS a FROM c",
+		},
+		{name: "too-few-values", text: "SELECT * FROM c WHERE c.id = ? AND c.x = ?", values: []interface{}{"1"}, err: true},
+		{name: "too-many-values", text: "SELECT * FROM c WHERE c.id = ?", values: []interface{}{"1", "2"}, err: true},
+	}
+	for _, ex := range examples {
+		t.Run(ex.name, func(t *testing.T) {
+			query, err := interstellar.NewQueryBuilder(ex.text).Bind(ex.values...).Build()
+			if ex.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", query)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query.Query != ex.expected {
+				t.Errorf("expected query '%s', got '%s'", ex.expected, query.Query)
+			}
+			if len(query.Parameters) != len(ex.values) {
+				t.Fatalf("expected %d parameters, got %d", len(ex.values), len(query.Parameters))
+			}
+			for i, p := range query.Parameters {
+				if p.Name != fmt.Sprintf("@p%d", i) || p.Value != ex.values[i] {
+					t.Errorf("unexpected parameter %s", p)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryPageBase64(t *testing.T) {
+	middle := `{"token":"+RID:YrMqAKFnpn5IAAAAAAAAAA==#RT:3#TRC:15","range":{"min":"","max":"FF"}}`
+	fake := testutil.NewFakeRequester().On(http.MethodPost, "/dbs/db1/colls/col1/docs",
+		testutil.Page(`{"Documents":[{"id":"1"},{"id":"2"}]}`, "first"),
+		testutil.Page(`{"Documents":[{"id":"3"},{"id":"4"}]}`, middle),
+		testutil.Page(`{"Documents":[{"id":"5"}]}`, ""))
+	cc := testutil.NewStubClient(fake).WithDatabase("db1").WithCollection("col1")
+	examples := []struct {
+		name     string
+		cursor   string
+		expected int
+		next     string
+	}{
+		{name: "first", cursor: "", expected: 2, next: interstellar.EncodeQueryCursor("first")},
+		{name: "middle", cursor: interstellar.EncodeQueryCursor("first"), expected: 2, next: interstellar.EncodeQueryCursor(middle)},
+		{name: "last", cursor: interstellar.EncodeQueryCursor(middle), expected: 1, next: ""},
+	}
+	for _, ex := range examples {
+		t.Run(ex.name, func(t *testing.T) {
+			query := &interstellar.Query{Query: "SELECT * FROM c", MaxItemCount: 2}
+			cont, err := interstellar.DecodeQueryCursor(ex.cursor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			query.Continuation = cont
+			results, next, err := cc.QueryPageBase64(context.Background(), query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != ex.expected {
+				t.Errorf("expected %d results, got %d", ex.expected, len(results))
+			}
+			if next != ex.next {
+				t.Errorf("expected next cursor '%s', got '%s'", ex.next, next)
+			}
+		})
+	}
+	for i, cont := range []string{"", "first", middle} {
+		if actual := fake.Requests()[i].Header.Get(interstellar.HeaderContinuation); actual != cont {
+			t.Errorf("request %d: expected continuation '%s', got '%s'", i, cont, actual)
+		}
+	}
+	if _, err := interstellar.DecodeQueryCursor("not a cursor!"); err == nil {
+		t.Errorf("expected invalid cursor to fail decoding")
+	}
+}
+
+func TestQueryEnableScan(t *testing.T) {
+	client := testutil.NewStubClient(nil)
+	for _, scan := range []bool{false, true} {
+		query := &interstellar.Query{
+			Query:      "SELECT * FROM c WHERE c.unindexed = 1",
+			EnableScan: scan,
+		}
+		req, err := client.NewHTTPRequest(nil, interstellar.ClientRequest{
+			Method:       http.MethodPost,
+			Path:         "/dbs/db1/colls/col1/docs",
+			ResourceType: interstellar.ResourceDocuments,
+			Options:      query,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := ""
+		if scan {
+			expected = "true"
+		}
+		if actual := req.Header.Get(interstellar.HeaderDocDBQueryEnableScan); actual != expected {
+			t.Errorf("EnableScan=%t: expected header %q, got %q", scan, expected, actual)
+		}
+	}
+}
+
+func TestQueryContinuationTokenLimitKB(t *testing.T) {
+	client := testutil.NewStubClient(nil)
+	for _, limit := range []int{0, 4} {
+		query := &interstellar.Query{
+			Query:                    "SELECT * FROM c",
+			EnableCrossPartition:     true,
+			ContinuationTokenLimitKB: limit,
+		}
+		req, err := client.NewHTTPRequest(nil, interstellar.ClientRequest{
+			Method:       http.MethodPost,
+			Path:         "/dbs/db1/colls/col1/docs",
+			ResourceType: interstellar.ResourceDocuments,
+			Options:      query,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := ""
+		if limit > 0 {
+			expected = "4"
+		}
+		if actual := req.Header.Get(interstellar.HeaderDocDBResponseContinuationTokenLimitKB); actual != expected {
+			t.Errorf("ContinuationTokenLimitKB=%d: expected header %q, got %q", limit, expected, actual)
+		}
+	}
+}
+
+func TestConsistencyLevelFields(t *testing.T) {
+	client := testutil.NewStubClient(nil)
+	header := func(opts interstellar.RequestOptions) string {
+		req, err := client.NewHTTPRequest(nil, interstellar.ClientRequest{
+			Method:       http.MethodPost,
+			Path:         "/dbs/db1/colls/col1/docs",
+			ResourceType: interstellar.ResourceDocuments,
+			Options:      opts,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req.Header.Get(interstellar.HeaderConsistencyLevel)
+	}
+	tests := []struct {
+		name     string
+		opts     interstellar.RequestOptions
+		expected string
+	}{
+		{name: "query", opts: &interstellar.Query{ConsistencyLevel: interstellar.ConsistencyEventual}, expected: "Eventual"},
+		{name: "query deprecated", opts: &interstellar.Query{ConsistencytLevel: interstellar.ConsistencyEventual}, expected: "Eventual"},
+		{name: "query both", opts: &interstellar.Query{ConsistencyLevel: interstellar.ConsistencySession, ConsistencytLevel: interstellar.ConsistencyEventual}, expected: "Session"},
+		{name: "common", opts: &interstellar.CommonRequestOptions{ConsistencyLevel: interstellar.ConsistencyEventual}, expected: "Eventual"},
+		{name: "common deprecated", opts: &interstellar.CommonRequestOptions{ConsistencytLevel: interstellar.ConsistencyEventual}, expected: "Eventual"},
+		{name: "common both", opts: &interstellar.CommonRequestOptions{ConsistencyLevel: interstellar.ConsistencySession, ConsistencytLevel: interstellar.ConsistencyEventual}, expected: "Session"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := header(test.opts); actual != test.expected {
+				t.Errorf("expected consistency level %q, got %q", test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package interstellar
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultTransientRetries is the number of times NewClient will retry a request which fails with a transient network error
+	DefaultTransientRetries = 3
+	// DefaultTransientBackoff is the initial delay NewClient will wait before retrying a request which failed with a transient network error
+	DefaultTransientBackoff = 100 * time.Millisecond
+	// DefaultPageThrottleRetries is the number of times ListResources retries a throttled page, when Client.PageThrottleRetries is not set
+	DefaultPageThrottleRetries = 5
+	// DefaultPageThrottleBackoff is the initial delay ListResources waits before retrying a throttled page which did not include x-ms-retry-after-ms
+	DefaultPageThrottleBackoff = 500 * time.Millisecond
+)
+
+// TransientRetryRequester is a Requester which retries requests that fail with a transient network error,
+// such as a connection reset, an unexpected EOF, or a DNS/dial failure.
+// HTTP error statuses are never retried here; they are returned as a normal response.
+//
+// Retries are deterministic: the n-th retry waits Backoff * 2^(n-1) with no jitter.
+// Waiting is cancelled if the request context is done.
+//
+// Only requests which are safe to send again are retried.
+// Requests with a body are never retried unless the body can be re-created with GetBody (NewHTTPRequest always sets GetBody).
+//
+// - GET, HEAD, and DELETE requests, as well as queries (POST requests with the x-ms-documentdb-isquery header), are assumed to be idempotent
+// and are retried on any transient error. Note that a retried DELETE may return 404 Not Found if the first attempt succeeded on the server.
+//
+// - Any other request (such as a POST create or a PUT replace) is only retried if the error happened while connecting
+// (dial or DNS errors), before any bytes of the request were written.
+// A connection reset or EOF on these requests is NOT retried, because the server may have already applied the write.
+type TransientRetryRequester struct {
+	Requester
+	// MaxRetries is the maximum number of times a request is retried after the initial attempt
+	MaxRetries int
+	// Backoff is the delay before the first retry. It is doubled on each subsequent retry.
+	Backoff time.Duration
+}
+
+// Do sends the request, retrying on transient network errors
+func (r *TransientRetryRequester) Do(req *http.Request) (*http.Response, error) {
+	resp, err := r.Requester.Do(req)
+	for attempt := 0; err != nil && attempt < r.MaxRetries; attempt++ {
+		transient, unsent := transientError(err)
+		if !transient {
+			return resp, err
+		}
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			// the body was consumed by the failed attempt and cannot be re-sent
+			return resp, err
+		}
+		if !unsent && !requestIsIdempotent(req) {
+			return resp, err
+		}
+		if req.GetBody != nil {
+			body, berr := req.GetBody()
+			if berr != nil {
+				return resp, err
+			}
+			nreq := *req
+			nreq.Body = body
+			req = &nreq
+		}
+		select {
+		case <-req.Context().Done():
+			return resp, err
+		case <-time.After(r.Backoff << uint(attempt)):
+		}
+		resp, err = r.Requester.Do(req)
+	}
+	return resp, err
+}
+
+func requestIsIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		return req.Header.Get(HeaderDocDBIsQuery) == "true"
+	default:
+		return false
+	}
+}
+
+// transientError reports whether err is a transient network error,
+// and whether the error is known to have happened before the request was written (unsent)
+func transientError(err error) (transient bool, unsent bool) {
+	if uerr, ok := err.(*url.Error); ok {
+		err = uerr.Err
+	}
+	switch e := err.(type) {
+	case *net.DNSError:
+		return true, true
+	case *net.OpError:
+		if e.Op == "dial" {
+			return true, true
+		}
+		if serr, ok := e.Err.(*os.SyscallError); ok && serr.Err == syscall.ECONNRESET {
+			return true, false
+		}
+		return false, false
+	}
+	return err == io.EOF || err == io.ErrUnexpectedEOF, false
+}
+
+func (c *Client) pageThrottleRetries() int {
+	switch {
+	case c.PageThrottleRetries < 0:
+		return 0
+	case c.PageThrottleRetries == 0:
+		return DefaultPageThrottleRetries
+	default:
+		return c.PageThrottleRetries
+	}
+}
+
+// throttleBackoff is the delay before retrying a throttled request
+// The server's x-ms-retry-after-ms is honored; otherwise the n-th retry waits DefaultPageThrottleBackoff * 2^n
+func (c *Client) throttleBackoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+	return DefaultPageThrottleBackoff << uint(attempt)
+}
+
+// sleepContext waits for the duration, or until the context is done, in which case the context's error is returned
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if ctx == nil {
+		time.Sleep(d)
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
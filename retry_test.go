@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package interstellar_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/jet/go-interstellar"
+	"github.com/jet/go-interstellar/internal/testutil"
+)
+
+func TestTransientRetryRequester(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "localhost"}}
+	examples := []struct {
+		name     string
+		method   string
+		query    bool
+		getBody  bool
+		errs     []error
+		attempts int
+		success  bool
+	}{
+		{name: "get-eof", method: http.MethodGet, errs: []error{io.EOF, io.EOF}, attempts: 3, success: true},
+		{name: "get-exhausted", method: http.MethodGet, errs: []error{io.EOF, io.EOF, io.EOF, io.EOF}, attempts: 3, success: false},
+		{name: "query-eof", method: http.MethodPost, query: true, getBody: true, errs: []error{io.ErrUnexpectedEOF}, attempts: 2, success: true},
+		{name: "query-eof-no-getbody", method: http.MethodPost, query: true, errs: []error{io.ErrUnexpectedEOF}, attempts: 1, success: false},
+		{name: "post-eof", method: http.MethodPost, getBody: true, errs: []error{io.EOF}, attempts: 1, success: false},
+		{name: "post-dial", method: http.MethodPost, getBody: true, errs: []error{dialErr}, attempts: 2, success: true},
+		{name: "post-dial-no-getbody", method: http.MethodPost, errs: []error{dialErr}, attempts: 1, success: false},
+		{name: "get-other", method: http.MethodGet, errs: []error{interstellar.Error("boom")}, attempts: 1, success: false},
+	}
+	for _, ex := range examples {
+		t.Run(ex.name, func(t *testing.T) {
+			attempts := 0
+			r := &interstellar.TransientRetryRequester{
+				MaxRetries: 2,
+				Requester: testutil.RequesterFunc(func(req *http.Request) (*http.Response, error) {
+					attempts++
+					if req.Body != nil {
+						if body, _ := ioutil.ReadAll(req.Body); string(body) != "{}" {
+							t.Errorf("attempt %d: expected body '{}', got '%s'", attempts, string(body))
+						}
+					}
+					if attempts <= len(ex.errs) {
+						return nil, ex.errs[attempts-1]
+					}
+					return testutil.NewResponse(req, http.StatusOK, nil, "{}"), nil
+				}),
+			}
+			var body io.Reader
+			if ex.method != http.MethodGet {
+				body = bytes.NewBufferString("{}")
+			}
+			req, _ := http.NewRequest(ex.method, "https://localhost:8081/dbs", body)
+			if !ex.getBody {
+				req.GetBody = nil
+			}
+			if ex.query {
+				req.Header.Set(interstellar.HeaderDocDBIsQuery, "true")
+			}
+			_, err := r.Do(req)
+			if ex.success && err != nil {
+				t.Errorf("expected success, got %v", err)
+			} else if !ex.success && err == nil {
+				t.Errorf("expected error")
+			}
+			if attempts != ex.attempts {
+				t.Errorf("expected %d attempts, got %d", ex.attempts, attempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+
+package interstellar
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// RUAccumulator sums the request units (RU) charged for every request made with a context from WithRUAccumulator
+// It is safe for concurrent use.
+type RUAccumulator struct {
+	mu    sync.Mutex
+	total float64
+}
+
+// Total returns the request units charged so far
+func (a *RUAccumulator) Total() float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.total
+}
+
+func (a *RUAccumulator) add(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	charge, err := strconv.ParseFloat(resp.Header.Get(HeaderRequestCharge), 64)
+	if err != nil {
+		return
+	}
+	a.mu.Lock()
+	a.total += charge
+	a.mu.Unlock()
+}
+
+type ruAccumulatorKey struct{}
+
+// WithRUAccumulator returns a context which makes the Client add the request charge of each response to the returned accumulator
+// This gives the total cost of a logical operation spanning several calls, such as the handling of one user request:
+//
+//     ctx, ru := interstellar.WithRUAccumulator(ctx)
+//     err := handle(ctx, client)
+//     log.Printf("request used %.2f RU", ru.Total())
+//
+// Charges are added for every response the Client receives, including error responses that carry a charge.
+// Retries made by the Client's Requester are only counted once, since only the final response is seen.
+func WithRUAccumulator(ctx context.Context) (context.Context, *RUAccumulator) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	acc := &RUAccumulator{}
+	return context.WithValue(ctx, ruAccumulatorKey{}, acc), acc
+}
+
+func ruAccumulatorFrom(ctx context.Context) *RUAccumulator {
+	acc, _ := ctx.Value(ruAccumulatorKey{}).(*RUAccumulator)
+	return acc
+}
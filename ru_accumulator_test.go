@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+
+package interstellar_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/jet/go-interstellar"
+	"github.com/jet/go-interstellar/internal/testutil"
+)
+
+func TestWithRUAccumulator(t *testing.T) {
+	hdr := http.Header{}
+	hdr.Set(interstellar.HeaderRequestCharge, "1.25")
+	fake := testutil.NewFakeRequester().
+		On(http.MethodGet, "/dbs/missing", testutil.FakeResponse{StatusCode: http.StatusNotFound, Header: hdr, Body: `{}`})
+	for _, id := range []string{"db1", "db2", "db3"} {
+		fake.On(http.MethodGet, "/dbs/"+id, testutil.FakeResponse{StatusCode: http.StatusOK, Header: hdr, Body: `{"id":"` + id + `"}`})
+	}
+	client := testutil.NewStubClient(fake)
+	ctx, ru := interstellar.WithRUAccumulator(context.Background())
+	if _, _, err := client.WithDatabase("db1").GetRaw(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.WithDatabase("db2").GetRaw(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.WithDatabase("missing").GetRaw(ctx, nil); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, _, err := client.WithDatabase("db3").GetRaw(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := ru.Total(); got != 3.75 {
+		t.Errorf("expected a total of 3.75 RU, got %v", got)
+	}
+}
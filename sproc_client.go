@@ -22,7 +22,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"net/url"
 )
 
 // StoredProcedureResource represents a Stored Procedure in Cosmos DB
@@ -71,7 +70,7 @@ func (c *CollectionClient) CreateStoredProcedure(ctx context.Context, req Create
 }
 
 func (c *CollectionClient) createStoredProcedureRaw(ctx context.Context, req CreateStoredProcedureRequest) ([]byte, *ResponseMetadata, error) {
-	rl := fmt.Sprintf("dbs/%s/colls/%s", url.PathEscape(c.DatabaseID), url.PathEscape(c.CollectionID))
+	rl := c.ResourceLink()
 	body, err := json.Marshal(&req)
 	if err != nil {
 		return nil, nil, err
@@ -120,6 +119,10 @@ type SProcClient struct {
 	DatabaseID   string
 	CollectionID string
 	SProcID      string
+
+	// PartitionKey is the partition key the stored procedure is executed in
+	// This is required to execute stored procedures in partitioned collections.
+	PartitionKey []string
 }
 
 // WithStoredProcedure creates a SProcClient for the given Stored Procedure within this Collection
@@ -132,9 +135,16 @@ func (c *CollectionClient) WithStoredProcedure(id string) *SProcClient {
 	}
 }
 
+// WithPartitionKey creates a copy of the SProcClient which executes the stored procedure in the given partition
+func (c *SProcClient) WithPartitionKey(partitionKey []string) *SProcClient {
+	sc := *c
+	sc.PartitionKey = partitionKey
+	return &sc
+}
+
 // ResourceLink gets the resource link for the stored procedure
 func (c *SProcClient) ResourceLink() string {
-	return fmt.Sprintf("dbs/%s/colls/%s/sprocs/%s", url.PathEscape(c.DatabaseID), url.PathEscape(c.CollectionID), url.PathEscape(c.SProcID))
+	return BuildResourceLink("dbs", c.DatabaseID, "colls", c.CollectionID, "sprocs", c.SProcID)
 }
 
 // Replace replaces a Stored Procedure Body with the new one
@@ -170,6 +180,7 @@ func (c *SProcClient) replaceRaw(ctx context.Context, body string, opts RequestO
 }
 
 // Execute the stored procedure and return the raw result body
+// The stored procedure is executed in the client's PartitionKey, if set (see WithPartitionKey).
 func (c *SProcClient) Execute(ctx context.Context, opts RequestOptions, args ...interface{}) ([]byte, *ResponseMetadata, error) {
 	rl := c.ResourceLink()
 	bs, err := json.Marshal(args)
@@ -182,7 +193,7 @@ func (c *SProcClient) Execute(ctx context.Context, opts RequestOptions, args ...
 		Path:         fmt.Sprintf("/%s", rl),
 		ResourceType: ResourceStoredProcedures,
 		ResourceLink: rl,
-		Options:      opts,
+		Options:      addPartitionKey(opts, c.PartitionKey),
 		Body:         bytes.NewBuffer(bs),
 	})
 }
@@ -196,6 +207,19 @@ func (c *SProcClient) Func(opts RequestOptions) func(context.Context, ...interfa
 	}
 }
 
+// PartitionedFunc is like Func, but the returned function takes the partition key to execute the stored procedure in as its second parameter
+// This allows a single prepared stored procedure to be executed across different partitions, such as a "transfer funds" procedure keyed by account:
+//
+//     transfer := spc.PartitionedFunc(nil)
+//     body, meta, err := transfer(ctx, []string{accountID}, from, to, amount)
+//
+// The per-call partition key takes precedence over the client's PartitionKey.
+func (c *SProcClient) PartitionedFunc(opts RequestOptions) func(context.Context, []string, ...interface{}) ([]byte, *ResponseMetadata, error) {
+	return func(ctx context.Context, partitionKey []string, args ...interface{}) ([]byte, *ResponseMetadata, error) {
+		return c.WithPartitionKey(partitionKey).Execute(ctx, opts, args...)
+	}
+}
+
 // Delete deletes the stored procedure
 func (c *SProcClient) Delete(ctx context.Context, opts RequestOptions) (bool, *ResponseMetadata, error) {
 	rl := c.ResourceLink()
@@ -206,3 +230,11 @@ func (c *SProcClient) Delete(ctx context.Context, opts RequestOptions) (bool, *R
 		Options:      opts,
 	})
 }
+
+// WithOptions creates a copy of the SProcClient which applies the options to every request made by it
+// See Client.WithOptions
+func (c *SProcClient) WithOptions(opts RequestOptions) *SProcClient {
+	cc := *c
+	cc.Client = c.Client.WithOptions(opts)
+	return &cc
+}
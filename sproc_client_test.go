@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+package interstellar_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/jet/go-interstellar"
+	"github.com/jet/go-interstellar/internal/testutil"
+)
+
+func TestSProcExecuteWithPartitionKey(t *testing.T) {
+	fake := testutil.NewFakeRequester().On(http.MethodPost, "/dbs/db1/colls/col1/sprocs/hello", testutil.Page(`"Hello, World"`, ""))
+	spc := testutil.NewStubClient(fake).WithDatabase("db1").WithCollection("col1").WithStoredProcedure("hello")
+	if _, _, err := spc.Execute(context.Background(), nil, "World"); err != nil {
+		t.Fatal(err)
+	}
+	if partitionKey := fake.Requests()[0].Header.Get(interstellar.HeaderDocDBPartitionKey); partitionKey != "" {
+		t.Errorf("expected no partition key, got '%s'", partitionKey)
+	}
+	fn := spc.WithPartitionKey([]string{"tenant1"}).Func(nil)
+	body, _, err := fn(context.Background(), "World")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if partitionKey := fake.Requests()[1].Header.Get(interstellar.HeaderDocDBPartitionKey); partitionKey != `["tenant1"]` {
+		t.Errorf("expected partition key '[\"tenant1\"]', got '%s'", partitionKey)
+	}
+	if string(body) != `"Hello, World"` {
+		t.Errorf("unexpected result %s", body)
+	}
+	if spc.PartitionKey != nil {
+		t.Errorf("expected the original client to be unchanged")
+	}
+	for _, req := range fake.Requests() {
+		if req.Body != `["World"]` {
+			t.Errorf("unexpected arguments %s", req.Body)
+		}
+	}
+}
+
+func TestSProcPartitionedFunc(t *testing.T) {
+	fake := testutil.NewFakeRequester().On(http.MethodPost, "/dbs/db1/colls/col1/sprocs/transfer", testutil.Page(`true`, ""))
+	spc := testutil.NewStubClient(fake).WithDatabase("db1").WithCollection("col1").WithStoredProcedure("transfer")
+	transfer := spc.PartitionedFunc(&interstellar.CommonRequestOptions{ConsistencyLevel: interstellar.ConsistencySession})
+	for _, account := range []string{"a1", "a2"} {
+		if _, _, err := transfer(context.Background(), []string{account}, "from", "to", 100); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var partitionKeys []string
+	for _, req := range fake.Requests() {
+		partitionKeys = append(partitionKeys, req.Header.Get(interstellar.HeaderDocDBPartitionKey))
+		if req.Header.Get(interstellar.HeaderConsistencyLevel) != string(interstellar.ConsistencySession) {
+			t.Errorf("expected the default options to be applied")
+		}
+	}
+	if len(partitionKeys) != 2 || partitionKeys[0] != `["a1"]` || partitionKeys[1] != `["a2"]` {
+		t.Errorf("unexpected partition keys %v", partitionKeys)
+	}
+}
@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+
+package interstellar
+
+import (
+	"context"
+	"net/http"
+)
+
+// RequestTap observes the http request and response of a single call; resp is nil if the Requester returned an error
+type RequestTap func(req *http.Request, resp *http.Response)
+
+type requestTapKey struct{}
+
+// WithRequestTap returns a context which makes the Client call the tap with each http request made with it, and its response
+// This captures the exchange of a single operation for debugging, without replacing the Client's Requester:
+//
+//     ctx = interstellar.WithRequestTap(ctx, func(req *http.Request, resp *http.Response) {
+//         dump, _ := httputil.DumpResponse(resp, true)
+//         log.Printf("%s %s\n%s", req.Method, req.URL, dump)
+//     })
+//     _, _, err := dc.GetRaw(ctx, nil)
+//
+// The tap is called once for each request the Client sends, after the Requester returns (including any retries it made),
+// and before the response body is read by the Client. If the tap reads the body, it must replace it with an equivalent one.
+// The request body has already been sent, but can be read again with req.GetBody.
+func WithRequestTap(ctx context.Context, tap RequestTap) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, requestTapKey{}, tap)
+}
+
+func requestTapFrom(ctx context.Context) RequestTap {
+	tap, _ := ctx.Value(requestTapKey{}).(RequestTap)
+	return tap
+}
@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright (c) 2019-present, Jet.com, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License."
+
+
+package interstellar_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/jet/go-interstellar"
+	"github.com/jet/go-interstellar/internal/testutil"
+)
+
+func TestWithRequestTap(t *testing.T) {
+	client := testutil.NewStubClient(testutil.NewFakeRequester().
+		On(http.MethodGet, "/dbs/db1", testutil.Page(`{"id":"/dbs/db1"}`, "")).
+		On(http.MethodGet, "/dbs/db2", testutil.Page(`{"id":"/dbs/db2"}`, "")))
+	var tapped []string
+	ctx := interstellar.WithRequestTap(context.Background(), func(req *http.Request, resp *http.Response) {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		tapped = append(tapped, req.Method+" "+req.URL.Path+" "+string(body))
+	})
+	body, _, err := client.WithDatabase("db1").GetRaw(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"id":"/dbs/db1"}` {
+		t.Errorf("expected the body to be readable after the tap, got %s", body)
+	}
+	if _, _, err = client.WithDatabase("db2").GetRaw(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(tapped) != 1 || tapped[0] != `GET /dbs/db1 {"id":"/dbs/db1"}` {
+		t.Errorf("expected only the tapped call to be observed, got %v", tapped)
+	}
+}
@@ -22,7 +22,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"net/url"
 )
 
 // UserDefinedFunctionResource represents a User Defined Function in Cosmos DB
@@ -71,7 +70,7 @@ func (c *CollectionClient) CreateUserDefinedFunction(ctx context.Context, req Cr
 }
 
 func (c *CollectionClient) createUserDefinedFunctionRaw(ctx context.Context, req CreateUserDefinedFunctionRequest) ([]byte, *ResponseMetadata, error) {
-	rl := fmt.Sprintf("dbs/%s/colls/%s", url.PathEscape(c.DatabaseID), url.PathEscape(c.CollectionID))
+	rl := c.ResourceLink()
 	body, err := json.Marshal(&req)
 	if err != nil {
 		return nil, nil, err
@@ -134,7 +133,7 @@ func (c *CollectionClient) WithUDF(id string) *UDFClient {
 
 // ResourceLink gets the resource link for the user-defined function
 func (c *UDFClient) ResourceLink() string {
-	return fmt.Sprintf("dbs/%s/colls/%s/udfs/%s", url.PathEscape(c.DatabaseID), url.PathEscape(c.CollectionID), url.PathEscape(c.UDFID))
+	return BuildResourceLink("dbs", c.DatabaseID, "colls", c.CollectionID, "udfs", c.UDFID)
 }
 
 // Replace replaces a UDF Body with the new one
@@ -180,3 +179,11 @@ func (c *UDFClient) Delete(ctx context.Context, opts RequestOptions) (bool, *Res
 		Options:      opts,
 	})
 }
+
+// WithOptions creates a copy of the UDFClient which applies the options to every request made by it
+// See Client.WithOptions
+func (c *UDFClient) WithOptions(opts RequestOptions) *UDFClient {
+	cc := *c
+	cc.Client = c.Client.WithOptions(opts)
+	return &cc
+}